@@ -0,0 +1,12 @@
+data "swp_aipe_data_objects" "database_servers" {
+  type = "cloud-server"
+  filters = {
+    "environment" = "production"
+  }
+  sort  = ["name"]
+  limit = 50
+}
+
+output "database_server_ids" {
+  value = data.swp_aipe_data_objects.database_servers.ids
+}
@@ -5,3 +5,26 @@ resource "swp_aipe_data_object" "example_server" {
     "ip"   = "10.1.2.3"
   }
 }
+
+resource "swp_aipe_data_object" "example_cluster" {
+  type = "cloud-cluster"
+  properties_json = jsonencode({
+    "name"       = "cluster01.example",
+    "node_count" = 3,
+    "tags"       = ["production", "eu-central"],
+  })
+}
+
+# The server is linked to the hoster in the same request which creates it.
+resource "swp_aipe_data_object" "example_hosted_server" {
+  type = "cloud-server"
+  properties = {
+    "name" = "db02.example"
+  }
+
+  link {
+    link_name     = "servers-to-hoster"
+    relation_name = "hosted-by"
+    target_ids    = ["42"]
+  }
+}
@@ -0,0 +1,8 @@
+import {
+  to = swp_aipe_data_object_link.server_is_hosted
+  identity = {
+    source_id     = "42"
+    link_name     = "servers-to-hoster"
+    relation_name = "hosted-by"
+  }
+}
@@ -0,0 +1,4 @@
+# Links are imported by the system.id of the source object, the link name and
+# the relation name on the source objects side. Only the relation name may
+# contain slashes.
+terraform import swp_aipe_data_object_link.server_is_hosted "42/servers-to-hoster/hosted-by"
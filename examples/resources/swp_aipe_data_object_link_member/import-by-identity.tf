@@ -0,0 +1,9 @@
+import {
+  to = swp_aipe_data_object_link_member.server_is_hosted
+  identity = {
+    source_id     = "42"
+    link_name     = "servers-to-hoster"
+    relation_name = "hosts"
+    target_id     = "4711"
+  }
+}
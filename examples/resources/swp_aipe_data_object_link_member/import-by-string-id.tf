@@ -0,0 +1,4 @@
+import {
+  to = swp_aipe_data_object_link_member.server_is_hosted
+  id = "42/servers-to-hoster/hosts/4711"
+}
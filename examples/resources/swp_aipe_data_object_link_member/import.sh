@@ -0,0 +1,4 @@
+# Link members are imported by the system.id of the source object, the link
+# name, the relation name on the source objects side and the system.id of the
+# target object. Only the relation name may contain slashes.
+terraform import swp_aipe_data_object_link_member.server_is_hosted "42/servers-to-hoster/hosts/4711"
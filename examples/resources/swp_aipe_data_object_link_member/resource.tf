@@ -0,0 +1,21 @@
+resource "swp_aipe_data_object" "example_server" {
+  type = "cloud-server"
+  properties = {
+    "name" = "db01.example",
+    "ip"   = "10.1.2.3"
+  }
+}
+
+# The hoster is shared with other configurations, which link their own servers.
+data "swp_aipe_data_object" "example_hoster" {
+  id = "42"
+}
+
+resource "swp_aipe_data_object_link_member" "server_is_hosted" {
+  source_id = data.swp_aipe_data_object.example_hoster.id
+
+  link_name     = "servers-to-hoster"
+  relation_name = "hosts"
+
+  target_id = swp_aipe_data_object.example_server.id
+}
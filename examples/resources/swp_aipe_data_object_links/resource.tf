@@ -0,0 +1,39 @@
+resource "swp_aipe_data_object" "example_server" {
+  type = "cloud-server"
+  properties = {
+    "name" = "db01.example",
+    "ip"   = "10.1.2.3"
+  }
+}
+
+resource "swp_aipe_data_object" "example_hoster" {
+  type = "hoster"
+  properties = {
+    "name"            = "Cloud Provider Inc.",
+    "support_contact" = "https://support.cloudprovider.example"
+  }
+}
+
+resource "swp_aipe_data_object" "example_backup_hoster" {
+  type = "hoster"
+  properties = {
+    "name" = "Backup Provider Inc."
+  }
+}
+
+# Both relations are created in a single request.
+resource "swp_aipe_data_object_links" "server" {
+  source_id = swp_aipe_data_object.example_server.id
+
+  link {
+    link_name     = "servers-to-hoster"
+    relation_name = "hosted-by"
+    target_ids    = [swp_aipe_data_object.example_hoster.id]
+  }
+
+  link {
+    link_name     = "servers-to-backup-hoster"
+    relation_name = "backed-up-by"
+    target_ids    = [swp_aipe_data_object.example_backup_hoster.id]
+  }
+}
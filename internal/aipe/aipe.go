@@ -3,14 +3,15 @@ package aipe
 import (
 	"bytes"
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"time"
 
 	"github.com/Serviceware/terraform-provider-swp/internal/authenticator"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"golang.org/x/sync/semaphore"
+	"golang.org/x/time/rate"
 )
 
 type AIPEClient struct {
@@ -22,6 +23,24 @@ type AIPEClient struct {
 
 	// Add authenticator client to AIPEClient
 	Authenticator *authenticator.AuthenticatorClient
+
+	// MaxRetries is the number of times a failed request is retried.
+	MaxRetries int
+
+	// RetryMaxWait is the upper limit for the wait time between two retries.
+	RetryMaxWait time.Duration
+
+	// RateLimiter limits the requests sent to the AIPE, including retries.
+	// Requests are not limited if it is nil.
+	RateLimiter *rate.Limiter
+
+	// InFlight caps the number of concurrent requests to the AIPE. Requests
+	// are not capped if it is nil.
+	InFlight *semaphore.Weighted
+
+	// SensitiveProperties are the names of properties whose values are masked
+	// in the logs.
+	SensitiveProperties []string
 }
 
 func (c *AIPEClient) GetOIDCToken(ctx context.Context) (string, error) {
@@ -36,102 +55,156 @@ type ObjectAPIResponse struct {
 	DataObject map[string]interface{} `json:"dataObject"`
 }
 
-func (c *AIPEClient) GetObject(ctx context.Context, id string) (map[string]string, error) {
-	// Make a request to the AIPE API to get the object with the specified ID.
-	objectURL := fmt.Sprintf("%s/data/api/v1/objects/%s", c.URL, id)
+// DataObject is a data object with its properties as returned by the AIPE.
+type DataObject struct {
+	ID         string
+	System     SystemMetadata
+	Properties map[string]interface{}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", objectURL, nil)
-	if err != nil {
-		return nil, err
+	// ETag identifies the version of the object for optimistic locking.
+	// It is only set for objects read with [AIPEClient.GetObject].
+	ETag string
+}
+
+// SystemMetadata is the system block the AIPE maintains for every data object.
+type SystemMetadata struct {
+	ID         string
+	TypeName   string
+	CreatedAt  string
+	CreatedBy  string
+	ModifiedAt string
+	ModifiedBy string
+	Version    string
+}
+
+func (m *SystemMetadata) UnmarshalJSON(data []byte) error {
+	// Depending on the AIPE version some values are numbers or objects
+	// instead of strings, so they are decoded as raw values first.
+	var system struct {
+		ID         json.RawMessage `json:"id"`
+		TypeName   json.RawMessage `json:"typeName"`
+		CreatedAt  json.RawMessage `json:"createdAt"`
+		CreatedBy  json.RawMessage `json:"createdBy"`
+		ModifiedAt json.RawMessage `json:"modifiedAt"`
+		ModifiedBy json.RawMessage `json:"modifiedBy"`
+		Version    json.RawMessage `json:"version"`
 	}
-	token, err := c.GetOIDCToken(ctx)
-	if err != nil {
-		return nil, err
+	if err := json.Unmarshal(data, &system); err != nil {
+		return err
 	}
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 
-	tflog.Info(ctx, "reading object")
-	resp, err := c.HTTPClient.Do(req)
+	m.ID = rawToString(system.ID)
+	m.TypeName = rawToString(system.TypeName)
+	m.CreatedAt = rawToString(system.CreatedAt)
+	m.CreatedBy = rawToString(system.CreatedBy)
+	m.ModifiedAt = rawToString(system.ModifiedAt)
+	m.ModifiedBy = rawToString(system.ModifiedBy)
+	m.Version = rawToString(system.Version)
+	return nil
+}
 
+// rawToString returns JSON strings unquoted and all other values as JSON text.
+func rawToString(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return string(raw)
+}
+
+// newDataObject splits the system block off the properties of a data object.
+func newDataObject(properties map[string]interface{}) (*DataObject, error) {
+	object := &DataObject{Properties: properties}
+
+	if system, ok := properties["system"]; ok {
+		systemJSON, err := json.Marshal(system)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(systemJSON, &object.System); err != nil {
+			return nil, fmt.Errorf("unable to parse system block of data object: %w", err)
+		}
+		delete(properties, "system")
+	}
+
+	object.ID = object.System.ID
+	return object, nil
+}
+
+func (c *AIPEClient) GetObject(ctx context.Context, id string) (*DataObject, error) {
+	// Make a request to the AIPE API to get the object with the specified ID.
+	objectURL := fmt.Sprintf("%s/data/api/v1/objects/%s", c.URL, id)
+
+	ctx = c.logContext(ctx)
+	tflog.SubsystemInfo(ctx, LogSubsystem, "reading object", map[string]interface{}{"id": id})
+	bodyBytes, header, err := c.doWithHeaders(ctx, http.MethodGet, objectURL, nil, nil, http.StatusOK)
 	if err != nil {
 		return nil, err
 	}
 
-	defer resp.Body.Close()
+	decoder := json.NewDecoder(bytes.NewReader(bodyBytes))
+	decoder.UseNumber()
 
-	if resp.StatusCode != http.StatusOK {
-		tflog.Info(ctx, "get object failed", map[string]interface{}{"status": resp.StatusCode, "objectURL": objectURL})
-		return nil, &ApiError{StatusCode: resp.StatusCode, Message: fmt.Sprintf("unexpected status code: %d", resp.StatusCode)}
+	var object ObjectAPIResponse
+	if err := decoder.Decode(&object); err != nil {
+		return nil, err
+	}
+	if object.DataObject == nil {
+		object.DataObject = map[string]interface{}{}
 	}
 
-	bodyBytes, err := io.ReadAll(resp.Body)
+	dataObject, err := newDataObject(object.DataObject)
 	if err != nil {
 		return nil, err
 	}
+	if dataObject.ID == "" {
+		dataObject.ID = id
+	}
 
-	tflog.Info(ctx, "Successfully retrieved object", map[string]interface{}{"bytes": string(bodyBytes)})
+	// Older AIPE versions send no ETag, updates of their objects are not
+	// conditional then.
+	dataObject.ETag = header.Get("ETag")
 
-	var object ObjectAPIResponse
-	json.Unmarshal(bodyBytes, &object)
-	delete(object.DataObject, "system")
-	return convertPropertiesToString(object.DataObject), nil
+	tflog.SubsystemDebug(ctx, LogSubsystem, "Successfully retrieved object", map[string]interface{}{"id": dataObject.ID, "etag": dataObject.ETag}, propertyFields(dataObject.Properties))
+	return dataObject, nil
 }
 
 type ObjectCreateRequest struct {
 	Type       string                 `json:"typeName"`
 	DataObject map[string]interface{} `json:"dataObject"`
+	Links      []LinkDefinition       `json:"links,omitempty"`
 }
 
 type ObjectCreateResponse struct {
 	ID string `json:"dataObjectId"`
 }
 
-func (c *AIPEClient) CreateObject(ctx context.Context, objectType string, data map[string]string) (string, error) {
-	// Make a request to the AIPE API to create an object with the specified data.
+func (c *AIPEClient) CreateObject(ctx context.Context, objectType string, data map[string]interface{}) (string, error) {
+	return c.CreateObjectWithLinks(ctx, objectType, data, nil)
+}
 
+// CreateObjectWithLinks creates the object together with its links to other
+// objects, so the object never exists without them.
+func (c *AIPEClient) CreateObjectWithLinks(ctx context.Context, objectType string, data map[string]interface{}, links []LinkDefinition) (string, error) {
+	// Make a request to the AIPE API to create an object with the specified data.
 	objectURL := fmt.Sprintf("%s/data/api/v1/objects", c.URL)
 
 	requestObject := ObjectCreateRequest{
 		Type:       objectType,
-		DataObject: convertPropertiesFromString(data),
-	}
-	body, err := json.Marshal(requestObject)
-	if err != nil {
-		return "", err
+		DataObject: data,
+		Links:      links,
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", objectURL, bytes.NewBuffer(body))
+	ctx = c.logContext(ctx)
+	tflog.SubsystemInfo(ctx, LogSubsystem, "creating object", map[string]interface{}{"objectType": objectType, "links": len(links)})
+	respData, err := c.do(ctx, http.MethodPost, objectURL, requestObject, http.StatusCreated)
 	if err != nil {
 		return "", err
 	}
-	token, err := c.GetOIDCToken(ctx)
-	if err != nil {
-		return "", err
-	}
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
-	req.Header.Set("Content-Type", "application/json")
-
-	tflog.Info(ctx, "creating object", map[string]interface{}{"objectType": objectType})
-	resp, err := c.HTTPClient.Do(req)
-	if err != nil {
-		return "", err
-	}
-
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusCreated {
-		respData, _ := io.ReadAll(resp.Body)
-		blub := base64.StdEncoding.EncodeToString(respData)
-		tflog.Info(ctx, "create object failed", map[string]interface{}{"status": resp.StatusCode, "objectURL": objectURL, "respData": blub})
-		return "", &ApiError{StatusCode: resp.StatusCode, Message: fmt.Sprintf("unexpected status code: %d", resp.StatusCode)}
-	}
-
-	respData, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
-
-	tflog.Info(ctx, "Successfully created object", map[string]interface{}{"objectType": objectType, "data": data, "response": string(respData)})
 
 	var createResponse ObjectCreateResponse
 	err = json.Unmarshal(respData, &createResponse)
@@ -139,50 +212,47 @@ func (c *AIPEClient) CreateObject(ctx context.Context, objectType string, data m
 		return "", err
 	}
 
+	tflog.SubsystemDebug(ctx, LogSubsystem, "Successfully created object", map[string]interface{}{"objectType": objectType, "id": createResponse.ID}, propertyFields(data))
+
 	return createResponse.ID, nil
 }
 
 type ObjectUpdateRequest struct {
 	DataObject map[string]interface{} `json:"dataObject"`
+	Links      []LinkDefinition       `json:"links,omitempty"`
+}
+
+// UpdateObject updates the properties of the object. If etag is not empty, it
+// is sent as If-Match header and the update fails with a conflict if the
+// object has been modified in the meantime, see [ErrorIsConflict].
+func (c *AIPEClient) UpdateObject(ctx context.Context, id string, data map[string]interface{}, etag string) error {
+	return c.UpdateObjectWithLinks(ctx, id, data, nil, etag)
 }
 
-func (c *AIPEClient) UpdateObject(ctx context.Context, id string, data map[string]string) error {
+// UpdateObjectWithLinks works like [AIPEClient.UpdateObject], but also adds
+// and removes links of the object in the same request.
+func (c *AIPEClient) UpdateObjectWithLinks(ctx context.Context, id string, data map[string]interface{}, links []LinkDefinition, etag string) error {
 	// Make a request to the AIPE API to update the object with the specified ID.
 	objectURL := fmt.Sprintf("%s/data/api/v1/objects/%s", c.URL, id)
 
 	requestObject := ObjectUpdateRequest{
-		DataObject: convertPropertiesFromString(data),
-	}
-	body, err := json.Marshal(requestObject)
-	if err != nil {
-		return err
+		DataObject: data,
+		Links:      links,
 	}
-	req, err := http.NewRequestWithContext(ctx, "PATCH", objectURL, bytes.NewBuffer(body))
-	if err != nil {
-		return err
-	}
-	token, err := c.GetOIDCToken(ctx)
-	if err != nil {
-		return err
+
+	header := http.Header{}
+	if etag != "" {
+		header.Set("If-Match", etag)
 	}
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
-	req.Header.Set("Content-Type", "application/json")
 
-	tflog.Info(ctx, "updating object", map[string]interface{}{"id": id})
-	resp, err := c.HTTPClient.Do(req)
+	ctx = c.logContext(ctx)
+	tflog.SubsystemInfo(ctx, LogSubsystem, "updating object", map[string]interface{}{"id": id, "etag": etag, "links": len(links)})
+	_, _, err := c.doWithHeaders(ctx, http.MethodPatch, objectURL, header, requestObject, http.StatusOK, http.StatusNoContent)
 	if err != nil {
 		return err
 	}
 
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
-		respData, _ := io.ReadAll(resp.Body)
-		tflog.Info(ctx, "update object failed", map[string]interface{}{"status": resp.StatusCode, "objectURL": objectURL, "respData": string(respData)})
-		return &ApiError{StatusCode: resp.StatusCode, Message: fmt.Sprintf("unexpected status code: %d", resp.StatusCode)}
-	}
-
-	tflog.Info(ctx, "Successfully updated object", map[string]interface{}{"id": id, "data": data})
+	tflog.SubsystemDebug(ctx, LogSubsystem, "Successfully updated object", map[string]interface{}{"id": id}, propertyFields(data))
 
 	return nil
 }
@@ -191,31 +261,14 @@ func (c *AIPEClient) DeleteObject(ctx context.Context, id string) error {
 	// Make a request to the AIPE API to delete the object with the specified ID.
 	objectURL := fmt.Sprintf("%s/data/api/v1/objects/%s", c.URL, id)
 
-	req, err := http.NewRequestWithContext(ctx, "DELETE", objectURL, nil)
+	ctx = c.logContext(ctx)
+	tflog.SubsystemInfo(ctx, LogSubsystem, "deleting object", map[string]interface{}{"id": id})
+	_, err := c.do(ctx, http.MethodDelete, objectURL, nil, http.StatusNoContent)
 	if err != nil {
 		return err
 	}
-	token, err := c.GetOIDCToken(ctx)
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
-
-	tflog.Info(ctx, "deleting object", map[string]interface{}{"id": id})
-	resp, err := c.HTTPClient.Do(req)
-	if err != nil {
-		return err
-	}
-
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusNoContent {
-		respData, _ := io.ReadAll(resp.Body)
-		tflog.Info(ctx, "delete object failed", map[string]interface{}{"status": resp.StatusCode, "objectURL": objectURL, "respData": string(respData)})
-		return &ApiError{StatusCode: resp.StatusCode, Message: fmt.Sprintf("unexpected status code: %d", resp.StatusCode)}
-	}
 
-	tflog.Info(ctx, "Successfully deleted object", map[string]interface{}{"id": id})
+	tflog.SubsystemInfo(ctx, LogSubsystem, "Successfully deleted object", map[string]interface{}{"id": id})
 
 	return nil
 }
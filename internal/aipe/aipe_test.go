@@ -0,0 +1,75 @@
+package aipe
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestNewDataObjectSystemMetadata(t *testing.T) {
+	var properties map[string]interface{}
+	body := `{
+		"system": {
+			"id": "42",
+			"typeName": "server",
+			"createdAt": "2024-05-01T10:00:00Z",
+			"createdBy": {"id": "7", "name": "terraform"},
+			"modifiedAt": "2024-05-02T10:00:00Z",
+			"version": 3
+		},
+		"fqdn": "db01"
+	}`
+	if err := json.Unmarshal([]byte(body), &properties); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	object, err := newDataObject(properties)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := SystemMetadata{
+		ID:         "42",
+		TypeName:   "server",
+		CreatedAt:  "2024-05-01T10:00:00Z",
+		CreatedBy:  `{"id":"7","name":"terraform"}`,
+		ModifiedAt: "2024-05-02T10:00:00Z",
+		Version:    "3",
+	}
+	if object.System != expected {
+		t.Errorf("expected %+v, got %+v", expected, object.System)
+	}
+	if object.ID != "42" {
+		t.Errorf("expected id 42, got %s", object.ID)
+	}
+	if _, ok := object.Properties["system"]; ok || object.Properties["fqdn"] != "db01" {
+		t.Errorf("unexpected properties %v", object.Properties)
+	}
+}
+
+func TestObjectWithoutETagIsUpdatedUnconditionally(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			fmt.Fprint(w, `{"dataObject":{"system":{"id":"42","version":"3"}}}`)
+		case http.MethodPatch:
+			if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+				t.Errorf("expected no If-Match, got %q", ifMatch)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+
+	object, err := client.GetObject(context.Background(), "42")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if object.ETag != "" {
+		t.Errorf("expected no entity tag, got %q", object.ETag)
+	}
+	if err := client.UpdateObject(context.Background(), "42", map[string]interface{}{"name": "db01"}, object.ETag); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
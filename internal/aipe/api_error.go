@@ -1,26 +1,152 @@
 package aipe
 
 import (
-	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"slices"
+	"strings"
 
 	"github.com/hashicorp/errwrap"
-	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
 type ApiError struct {
 	StatusCode int
 	Message    string
+
+	// Title, Detail and Code are taken from the problem payload of the response, if any.
+	Title  string
+	Detail string
+	Code   string
+
+	// TraceID identifies the request in the AIPE logs.
+	TraceID string
+
+	// FieldErrors lists the validation errors of single properties.
+	FieldErrors []FieldError
+}
+
+// FieldError is a validation error the AIPE reported for a single property.
+type FieldError struct {
+	Field   string
+	Message string
+	Code    string
 }
 
 func (e *ApiError) Error() string {
-	return e.Message
+	var sb strings.Builder
+	sb.WriteString(e.Message)
+
+	var summary []string
+	for _, s := range []string{e.Code, e.Title, e.Detail} {
+		if s != "" && !slices.Contains(summary, s) {
+			summary = append(summary, s)
+		}
+	}
+	if len(summary) > 0 {
+		sb.WriteString(": ")
+		sb.WriteString(strings.Join(summary, " - "))
+	}
+
+	for _, fieldError := range e.FieldErrors {
+		sb.WriteString("\n")
+		sb.WriteString(fieldError.String())
+	}
+
+	if e.TraceID != "" {
+		sb.WriteString(fmt.Sprintf("\n(trace id: %s)", e.TraceID))
+	}
+	return sb.String()
+}
+
+func (e FieldError) String() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// problemResponse covers the error payloads returned by the AIPE: RFC 7807
+// problem details with validation errors and the default Spring error body.
+type problemResponse struct {
+	Title     string `json:"title"`
+	Detail    string `json:"detail"`
+	Error     string `json:"error"`
+	Message   string `json:"message"`
+	Code      string `json:"code"`
+	ErrorCode string `json:"errorCode"`
+	TraceID   string `json:"traceId"`
+	TraceID2  string `json:"trace_id"`
+
+	Errors      []problemFieldError `json:"errors"`
+	FieldErrors []problemFieldError `json:"fieldErrors"`
+	Violations  []problemFieldError `json:"violations"`
+}
+
+type problemFieldError struct {
+	Field          string `json:"field"`
+	Property       string `json:"property"`
+	PropertyName   string `json:"propertyName"`
+	Message        string `json:"message"`
+	DefaultMessage string `json:"defaultMessage"`
+	Detail         string `json:"detail"`
+	Code           string `json:"code"`
+}
+
+// newApiError creates an [ApiError] for an unexpected response. The body is
+// parsed on a best effort basis, unknown payloads only result in a less
+// detailed error.
+func newApiError(statusCode int, body []byte) *ApiError {
+	apiError := &ApiError{
+		StatusCode: statusCode,
+		Message:    fmt.Sprintf("unexpected status code: %d", statusCode),
+	}
+
+	// Fields with an unexpected type are skipped, the remaining ones are still decoded.
+	var problem problemResponse
+	var typeError *json.UnmarshalTypeError
+	if err := json.Unmarshal(body, &problem); err != nil && !errors.As(err, &typeError) {
+		return apiError
+	}
+
+	apiError.Title = firstNonEmpty(problem.Title, problem.Error)
+	apiError.Detail = firstNonEmpty(problem.Detail, problem.Message)
+	apiError.Code = firstNonEmpty(problem.ErrorCode, problem.Code)
+	apiError.TraceID = firstNonEmpty(problem.TraceID, problem.TraceID2)
+
+	for _, fieldErrors := range [][]problemFieldError{problem.Errors, problem.FieldErrors, problem.Violations} {
+		for _, fieldError := range fieldErrors {
+			apiError.FieldErrors = append(apiError.FieldErrors, FieldError{
+				Field:   strings.TrimPrefix(firstNonEmpty(fieldError.Field, fieldError.Property, fieldError.PropertyName), "dataObject."),
+				Message: firstNonEmpty(fieldError.Message, fieldError.DefaultMessage, fieldError.Detail, fieldError.Code),
+				Code:    fieldError.Code,
+			})
+		}
+	}
+
+	return apiError
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
 }
 
 func ErrorIsNotFound(err error) bool {
 	apiError, ok := errwrap.GetType(err, &ApiError{}).(*ApiError)
 
-	tflog.Info(context.TODO(), "ErrorIsNotFound", map[string]interface{}{"apiError": apiError, "ok": ok})
-
 	return ok && apiError != nil && (apiError.StatusCode == http.StatusNotFound || apiError.StatusCode == http.StatusGone)
 }
+
+// ErrorIsConflict reports whether the request failed because the object has
+// been modified since it was read.
+func ErrorIsConflict(err error) bool {
+	apiError, ok := errwrap.GetType(err, &ApiError{}).(*ApiError)
+
+	return ok && apiError != nil && (apiError.StatusCode == http.StatusConflict || apiError.StatusCode == http.StatusPreconditionFailed)
+}
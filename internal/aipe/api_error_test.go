@@ -0,0 +1,69 @@
+package aipe
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewApiErrorProblemDetails(t *testing.T) {
+	body := `{
+		"type": "about:blank",
+		"title": "Bad Request",
+		"status": 400,
+		"detail": "Validation failed",
+		"errorCode": "DATA_OBJECT_INVALID",
+		"traceId": "4bf92f3577b34da6",
+		"errors": [
+			{"field": "dataObject.oof", "message": "unknown property"},
+			{"property": "fqdn", "code": "REQUIRED"}
+		]
+	}`
+
+	apiError := newApiError(400, []byte(body))
+
+	if apiError.Title != "Bad Request" || apiError.Detail != "Validation failed" || apiError.Code != "DATA_OBJECT_INVALID" || apiError.TraceID != "4bf92f3577b34da6" {
+		t.Errorf("unexpected problem details: %+v", apiError)
+	}
+
+	expectedFieldErrors := []FieldError{
+		{Field: "oof", Message: "unknown property"},
+		{Field: "fqdn", Message: "REQUIRED", Code: "REQUIRED"},
+	}
+	if len(apiError.FieldErrors) != len(expectedFieldErrors) {
+		t.Fatalf("expected %d field errors, got %d", len(expectedFieldErrors), len(apiError.FieldErrors))
+	}
+	for i, expected := range expectedFieldErrors {
+		if apiError.FieldErrors[i] != expected {
+			t.Errorf("expected field error %+v, got %+v", expected, apiError.FieldErrors[i])
+		}
+	}
+
+	message := apiError.Error()
+	for _, expected := range []string{"unexpected status code: 400", "Validation failed", "oof: unknown property", "trace id: 4bf92f3577b34da6"} {
+		if !strings.Contains(message, expected) {
+			t.Errorf("expected %q in error message %q", expected, message)
+		}
+	}
+}
+
+func TestNewApiErrorSpringDefault(t *testing.T) {
+	body := `{"timestamp":"2024-01-01T00:00:00Z","status":404,"error":"Not Found","message":"Data object 42 not found","path":"/data/api/v1/objects/42"}`
+
+	apiError := newApiError(404, []byte(body))
+
+	if apiError.Error() != "unexpected status code: 404: Not Found - Data object 42 not found" {
+		t.Errorf("unexpected error message %q", apiError.Error())
+	}
+	if !ErrorIsNotFound(apiError) {
+		t.Errorf("expected error to be not found")
+	}
+}
+
+func TestNewApiErrorUnknownBody(t *testing.T) {
+	for _, body := range []string{"", "<html>Bad Gateway</html>", `{"errors":"not a list","detail":"broken"}`} {
+		apiError := newApiError(502, []byte(body))
+		if !strings.HasPrefix(apiError.Error(), "unexpected status code: 502") {
+			t.Errorf("unexpected error message %q for body %q", apiError.Error(), body)
+		}
+	}
+}
@@ -1,13 +1,13 @@
 package aipe
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"net/url"
 	"slices"
+	"strconv"
 
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
@@ -22,49 +22,34 @@ type GetDataObjectLinksResponse struct {
 }
 
 func (c *AIPEClient) GetDataObjectLinks(ctx context.Context, id string, linkName string, relationName string) ([]string, error) {
+	ctx = c.logContext(ctx)
 	var objectIDs []string = nil
 	totalElements := 1
-	page := 0
 
-	for len(objectIDs) < totalElements {
+	for page := 0; len(objectIDs) < totalElements; page++ {
+		params := url.Values{}
+		params.Set("linkDefinitionName", linkName)
+		params.Set("relationName", relationName)
+		params.Set("page", strconv.Itoa(page))
+		objectURL := fmt.Sprintf("%s/data/api/v1/objects/%s/links?%s", c.URL, url.PathEscape(id), params.Encode())
 
-		// Make a request to the AIPE API to get the object with the specified ID.
-		objectURL := fmt.Sprintf("%s/data/api/v1/objects/%s/links?linkDefinitionName=%s&relationName=%s&page=%d", c.URL, id, linkName, relationName, page)
-		page++
-
-		req, err := http.NewRequestWithContext(ctx, "GET", objectURL, nil)
-		if err != nil {
-			return nil, err
-		}
-		token, err := c.GetOIDCToken(ctx)
+		tflog.SubsystemInfo(ctx, LogSubsystem, "Reading object links", map[string]interface{}{"url": logURL(objectURL), "id": id, "link_name": linkName, "relation_name": relationName, "page": page})
+		bodyBytes, err := c.do(ctx, http.MethodGet, objectURL, nil, http.StatusOK)
 		if err != nil {
 			return nil, err
 		}
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 
-		resp, err := c.HTTPClient.Do(req)
-		if err != nil {
-			return nil, err
-		}
-
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			return nil, &ApiError{StatusCode: resp.StatusCode, Message: fmt.Sprintf("unexpected status code: %d", resp.StatusCode)}
-		}
-
-		tflog.Info(ctx, "Reading object links", map[string]interface{}{"url": objectURL})
-		bodyBytes, err := io.ReadAll(resp.Body)
-		if err != nil {
+		var objectLinks GetDataObjectLinksResponse
+		if err := json.Unmarshal(bodyBytes, &objectLinks); err != nil {
 			return nil, err
 		}
 
-		var objectLinks GetDataObjectLinksResponse
-		json.Unmarshal(bodyBytes, &objectLinks)
-
-		if len(objectIDs) == 0 { // first iteration
+		if page == 0 {
 			totalElements = objectLinks.TotalElements
 		}
+		if len(objectLinks.Objects) == 0 { // the links changed while paginating
+			break
+		}
 
 		for _, object := range objectLinks.Objects {
 			objectIDs = append(objectIDs, object.System.ID)
@@ -87,47 +72,27 @@ type UpdateDataObjectLinksRequest struct {
 }
 
 func (c *AIPEClient) UpdateDataObjectLinks(ctx context.Context, id string, linkName string, relationName string, add []string, remove []string) error {
-	tflog.Info(ctx, "Updating data object links", map[string]interface{}{"url": c.URL, "id": id, "linkName": linkName, "relationName": relationName, "add": add, "remove": remove})
-	objectURL := fmt.Sprintf("%s/data/api/v1/objects/%s", c.URL, id)
-
-	payload := UpdateDataObjectLinksRequest{
-		Links: []LinkDefinition{
-			{
-				LinkName:     linkName,
-				RelationName: relationName,
-				Add:          add,
-				Remove:       remove,
-			},
+	return c.UpdateDataObjectLinksBatch(ctx, id, []LinkDefinition{
+		{
+			LinkName:     linkName,
+			RelationName: relationName,
+			Add:          add,
+			Remove:       remove,
 		},
-	}
-	payloadJSON, err := json.Marshal(payload)
-	if err != nil {
-		return err
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "PATCH", objectURL, bytes.NewBuffer(payloadJSON))
-	if err != nil {
-		return err
-	}
-	token, err := c.GetOIDCToken(ctx)
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
-	req.Header.Set("Content-Type", "application/json")
+	})
+}
 
-	tflog.Info(ctx, "Sending request to AIPE API", map[string]interface{}{"url": objectURL, "payload": string(payloadJSON)})
-	resp, err := c.HTTPClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
+// UpdateDataObjectLinksBatch adds and removes the links of several relations
+// of the object in a single request. The AIPE applies all of them or none.
+func (c *AIPEClient) UpdateDataObjectLinksBatch(ctx context.Context, id string, links []LinkDefinition) error {
+	ctx = c.logContext(ctx)
+	objectURL := fmt.Sprintf("%s/data/api/v1/objects/%s", c.URL, url.PathEscape(id))
+	tflog.SubsystemInfo(ctx, LogSubsystem, "Updating data object links", map[string]interface{}{"url": logURL(objectURL), "id": id, "links": len(links)})
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
-		responseBody, _ := io.ReadAll(resp.Body)
-		tflog.Info(ctx, "update object links failed", map[string]interface{}{"status": resp.StatusCode, "objectURL": objectURL, "response": string(responseBody)})
-		return &ApiError{StatusCode: resp.StatusCode, Message: fmt.Sprintf("unexpected status code: %d", resp.StatusCode)}
+	payload := UpdateDataObjectLinksRequest{
+		Links: links,
 	}
 
-	return nil
+	_, err := c.do(ctx, http.MethodPatch, objectURL, payload, http.StatusOK, http.StatusNoContent)
+	return err
 }
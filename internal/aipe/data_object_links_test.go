@@ -0,0 +1,51 @@
+package aipe
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync/atomic"
+	"testing"
+)
+
+func TestGetDataObjectLinksEscapesQuery(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.URL.Path != "/data/api/v1/objects/a b/links" || query.Get("linkDefinitionName") != "runs-on & #1" || query.Get("relationName") != "a+b" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		fmt.Fprint(w, `{"totalElements":1,"objects":[{"system":{"id":"7"}}]}`)
+	})
+
+	objectIDs, err := client.GetDataObjectLinks(context.Background(), "a b", "runs-on & #1", "a+b")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !slices.Equal(objectIDs, []string{"7"}) {
+		t.Errorf("expected [7], got %v", objectIDs)
+	}
+}
+
+func TestGetDataObjectLinksStopsAtEmptyPage(t *testing.T) {
+	var calls atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		// the server reports more links than it returns
+		if calls.Add(1) == 1 {
+			fmt.Fprint(w, `{"totalElements":3,"objects":[{"system":{"id":"2"}},{"system":{"id":"1"}}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"totalElements":3,"objects":[]}`)
+	})
+
+	objectIDs, err := client.GetDataObjectLinks(context.Background(), "42", "hosting", "hosts")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !slices.Equal(objectIDs, []string{"1", "2"}) {
+		t.Errorf("expected [1 2], got %v", objectIDs)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("expected 2 calls, got %d", calls.Load())
+	}
+}
@@ -0,0 +1,66 @@
+package aipe
+
+import (
+	"context"
+	"errors"
+	"net/url"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+const (
+	// LogSubsystem is the name of the tflog subsystem the client logs to.
+	LogSubsystem = "aipe"
+
+	// LogLevelEnvVar sets the log level of the subsystem independently of
+	// TF_LOG_PROVIDER.
+	LogLevelEnvVar = "TF_LOG_PROVIDER_SWP_AIPE"
+
+	// propertyFieldPrefix is prepended to property names logged as fields,
+	// so they cannot collide with the other fields of a log entry.
+	propertyFieldPrefix = "property."
+)
+
+// logContext returns ctx with the logger of the aipe subsystem, which masks
+// the values of all SensitiveProperties.
+func (c *AIPEClient) logContext(ctx context.Context) context.Context {
+	ctx = tflog.NewSubsystem(ctx, LogSubsystem, tflog.WithLevelFromEnv(LogLevelEnvVar), tflog.WithRootFields())
+
+	keys := make([]string, 0, len(c.SensitiveProperties))
+	for _, name := range c.SensitiveProperties {
+		keys = append(keys, propertyFieldPrefix+name)
+	}
+	return tflog.SubsystemMaskFieldValuesWithFieldKeys(ctx, LogSubsystem, keys...)
+}
+
+// propertyFields returns the properties as log fields. Each property is a
+// field of its own, so the values of sensitive properties can be masked.
+func propertyFields(properties map[string]interface{}) map[string]interface{} {
+	fields := make(map[string]interface{}, len(properties))
+	for name, value := range properties {
+		fields[propertyFieldPrefix+name] = value
+	}
+	return fields
+}
+
+// logURL returns the URL without its query, which contains the values of
+// search filters and query expressions.
+func logURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
+
+// logError returns the message of a failed request without the request URL,
+// which the HTTP client includes with its query.
+func logError(err error) string {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err.Error()
+	}
+	return err.Error()
+}
@@ -0,0 +1,21 @@
+package aipe
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+)
+
+func TestLogURL(t *testing.T) {
+	got := logURL("https://aipe.example/data/api/v1/objects?typeName=person&filter=email:jane@example.com")
+	if got != "https://aipe.example/data/api/v1/objects" {
+		t.Errorf("expected URL without query, got %s", got)
+	}
+}
+
+func TestLogError(t *testing.T) {
+	err := &url.Error{Op: "Get", URL: "https://aipe.example/objects?filter=email:jane@example.com", Err: errors.New("connection refused")}
+	if got := logError(err); got != "connection refused" {
+		t.Errorf("expected error without URL, got %s", got)
+	}
+}
@@ -1,8 +1,15 @@
 package aipe
 
-import "fmt"
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
 
-func convertPropertiesFromString(properties map[string]string) map[string]interface{} {
+// ConvertPropertiesFromString converts the string based property map used by
+// the `properties` attribute into the typed values expected by the AIPE. Only
+// the literals "true" and "false" are converted, everything else is sent as string.
+func ConvertPropertiesFromString(properties map[string]string) map[string]interface{} {
 	result := make(map[string]interface{})
 	for k, v := range properties {
 		if v == "false" {
@@ -16,18 +23,49 @@ func convertPropertiesFromString(properties map[string]string) map[string]interf
 	return result
 }
 
-func convertPropertiesToString(properties map[string]interface{}) map[string]string {
+// ConvertPropertiesToString converts typed AIPE property values into strings.
+// Properties without a value are skipped, non-string values are rendered as JSON.
+func ConvertPropertiesToString(properties map[string]interface{}) map[string]string {
 	result := make(map[string]string)
 	for k, v := range properties {
 		if v == nil {
 			continue
 		}
+		result[k] = ConvertPropertyToString(v)
+	}
+	return result
+}
 
-		if b, ok := v.(bool); ok {
-			result[k] = fmt.Sprintf("%t", b)
-		} else {
-			result[k] = v.(string)
+// ConvertPropertyToString renders a single typed AIPE property value as string.
+func ConvertPropertyToString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case bool:
+		return fmt.Sprintf("%t", v)
+	case json.Number:
+		return v.String()
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
 		}
+		return string(encoded)
 	}
-	return result
+}
+
+// DecodeProperties decodes a JSON object into a property map. Numbers are kept
+// as [json.Number] so that they round-trip without losing precision.
+func DecodeProperties(data []byte) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var properties map[string]interface{}
+	if err := decoder.Decode(&properties); err != nil {
+		return nil, err
+	}
+	if properties == nil {
+		return nil, fmt.Errorf("properties must be a JSON object")
+	}
+	return properties, nil
 }
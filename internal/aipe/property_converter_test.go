@@ -0,0 +1,60 @@
+package aipe
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestConvertPropertiesToString(t *testing.T) {
+	properties, err := DecodeProperties([]byte(`{"name":"db01","active":true,"cores":16,"ratio":0.5,"big":12345678901234567890,"tags":["a","b"],"owner":{"id":"1"},"empty":null,"text":"true"}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := map[string]string{
+		"name":   "db01",
+		"active": "true",
+		"cores":  "16",
+		"ratio":  "0.5",
+		"big":    "12345678901234567890",
+		"tags":   `["a","b"]`,
+		"owner":  `{"id":"1"}`,
+		"text":   "true",
+	}
+
+	result := ConvertPropertiesToString(properties)
+	if len(result) != len(expected) {
+		t.Errorf("expected %d properties, got %d", len(expected), len(result))
+	}
+	for k, v := range expected {
+		if result[k] != v {
+			t.Errorf("expected %s to be %q, got %q", k, v, result[k])
+		}
+	}
+}
+
+func TestDecodePropertiesRoundTrip(t *testing.T) {
+	input := `{"big":12345678901234567890,"empty":null,"nested":{"list":[1,"two",false]},"text":"true"}`
+
+	properties, err := DecodeProperties([]byte(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	encoded, err := json.Marshal(properties)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if string(encoded) != input {
+		t.Errorf("expected %s, got %s", input, encoded)
+	}
+}
+
+func TestDecodePropertiesRejectsNonObjects(t *testing.T) {
+	for _, input := range []string{`null`, `[]`, `"text"`, `{`} {
+		if _, err := DecodeProperties([]byte(input)); err == nil {
+			t.Errorf("expected error for %s", input)
+		}
+	}
+}
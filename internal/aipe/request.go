@@ -0,0 +1,212 @@
+package aipe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+const (
+	// DefaultMaxRetries is the number of retries used by the provider if nothing is configured.
+	DefaultMaxRetries = 4
+
+	// DefaultRetryMaxWait is the maximum wait time between two retries used by the provider
+	// if nothing is configured.
+	DefaultRetryMaxWait = 30 * time.Second
+
+	retryMinWait = 500 * time.Millisecond
+)
+
+// do sends a request to the AIPE API and returns the response body if the
+// response has one of the expected status codes. The payload is encoded as
+// JSON if it is not nil.
+//
+// Failed requests are retried with jittered exponential backoff, see
+// [isRetryable] for the conditions.
+func (c *AIPEClient) do(ctx context.Context, method string, url string, payload interface{}, expectedStatus ...int) ([]byte, error) {
+	body, _, err := c.doWithHeaders(ctx, method, url, nil, payload, expectedStatus...)
+	return body, err
+}
+
+// doWithHeaders works like [AIPEClient.do], but additionally sends the given
+// request headers and returns the response headers.
+func (c *AIPEClient) doWithHeaders(ctx context.Context, method string, url string, header http.Header, payload interface{}, expectedStatus ...int) ([]byte, http.Header, error) {
+	var body []byte
+	if payload != nil {
+		var err error
+		body, err = json.Marshal(payload)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		token, err := c.GetOIDCToken(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		resp, respBody, err := c.send(ctx, method, url, token, header, body)
+
+		if err == nil && slices.Contains(expectedStatus, resp.StatusCode) {
+			return respBody, resp.Header, nil
+		}
+
+		// The response body is not logged, it may repeat the submitted property values.
+		if err == nil {
+			apiError := newApiError(resp.StatusCode, respBody)
+			tflog.SubsystemInfo(ctx, LogSubsystem, "request failed", map[string]interface{}{"method": method, "url": logURL(url), "status": resp.StatusCode, "code": apiError.Code, "trace_id": apiError.TraceID, "attempt": attempt})
+			err = apiError
+		} else {
+			tflog.SubsystemInfo(ctx, LogSubsystem, "request failed", map[string]interface{}{"method": method, "url": logURL(url), "error": logError(err), "attempt": attempt})
+		}
+
+		if attempt >= c.MaxRetries || ctx.Err() != nil || !isRetryable(method, header.Get("If-Match") != "", resp) {
+			return nil, nil, err
+		}
+
+		wait := c.backoff(attempt, resp)
+		tflog.SubsystemInfo(ctx, LogSubsystem, "retrying request", map[string]interface{}{"method": method, "url": logURL(url), "wait": wait.String(), "attempt": attempt + 1})
+
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// send performs a single attempt of a request. The response is only returned
+// if the server answered, its body has already been read and closed.
+//
+// The attempt first waits for the rate limiter and then for a free slot of
+// the in-flight limit, so waiting for the rate does not block a slot.
+func (c *AIPEClient) send(ctx context.Context, method string, url string, token string, header http.Header, body []byte) (*http.Response, []byte, error) {
+	if c.RateLimiter != nil {
+		if err := c.RateLimiter.Wait(ctx); err != nil {
+			return nil, nil, err
+		}
+	}
+	if c.InFlight != nil {
+		if err := c.InFlight.Acquire(ctx, 1); err != nil {
+			return nil, nil, err
+		}
+		defer c.InFlight.Release(1)
+	}
+
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, values := range header {
+		for _, v := range values {
+			req.Header.Add(k, v)
+		}
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return resp, respBody, nil
+}
+
+// isRetryable decides whether a failed request is sent again. resp is nil if
+// the request failed without a response from the server.
+//
+// Idempotent requests are retried on transport errors, 429 and the 5xx codes
+// typically caused by overloaded servers or proxies. The AIPE treats PATCH
+// requests as idempotent, as they set absolute property values or add/remove
+// link targets. POST requests are only retried if the server signals that the
+// request has not been processed at all.
+//
+// Conditional requests are only retried on 429. If a failed attempt had been
+// applied nevertheless, the retry would fail its precondition and be reported
+// as a conflict, although nobody else modified the object.
+func isRetryable(method string, conditional bool, resp *http.Response) bool {
+	if conditional {
+		return resp != nil && resp.StatusCode == http.StatusTooManyRequests
+	}
+
+	idempotent := method != http.MethodPost
+
+	if resp == nil {
+		return idempotent
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotent
+	default:
+		return false
+	}
+}
+
+// backoff computes the wait time before the next retry. A Retry-After header
+// sent by the server takes precedence, but is capped at RetryMaxWait.
+func (c *AIPEClient) backoff(attempt int, resp *http.Response) time.Duration {
+	maxWait := c.RetryMaxWait
+	if maxWait <= 0 {
+		maxWait = DefaultRetryMaxWait
+	}
+
+	if resp != nil {
+		if wait, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+			return min(wait, maxWait)
+		}
+	}
+
+	wait := min(retryMinWait<<attempt, maxWait)
+	if wait <= 0 { // overflow for very high attempts
+		wait = maxWait
+	}
+
+	// Jitter within the upper half spreads out the retries of parallel requests.
+	return wait/2 + rand.N(wait/2+1)
+}
+
+// retryAfter parses the value of a Retry-After header, which is either a
+// number of seconds or an HTTP date.
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0), true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,232 @@
+package aipe
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Serviceware/terraform-provider-swp/internal/authenticator"
+	"golang.org/x/sync/semaphore"
+	"golang.org/x/time/rate"
+)
+
+// newTestClient starts a server which answers token requests itself and
+// passes all other requests to handler.
+func newTestClient(t *testing.T, handler http.HandlerFunc) *AIPEClient {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"exp":%d}`, time.Now().Add(time.Hour).Unix())))
+	token := "e30." + payload + ".signature"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/protocol/openid-connect/token" {
+			fmt.Fprintf(w, `{"access_token":%q}`, token)
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return &AIPEClient{
+		HTTPClient: server.Client(),
+		URL:        server.URL,
+		Authenticator: &authenticator.AuthenticatorClient{
+			Client:   server.Client(),
+			URL:      server.URL,
+			TokenURL: server.URL + "/protocol/openid-connect/token",
+		},
+		MaxRetries:   3,
+		RetryMaxWait: 10 * time.Millisecond,
+	}
+}
+
+func TestDoRetriesTransientErrors(t *testing.T) {
+	var calls atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusBadGateway)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			fmt.Fprint(w, `{"dataObject":{"name":"db01"}}`)
+		}
+	})
+
+	object, err := client.GetObject(context.Background(), "42")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if object.Properties["name"] != "db01" {
+		t.Errorf("expected name db01, got %v", object.Properties["name"])
+	}
+	if calls.Load() != 3 {
+		t.Errorf("expected 3 calls, got %d", calls.Load())
+	}
+}
+
+func TestDoGivesUpAfterMaxRetries(t *testing.T) {
+	var calls atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	err := client.DeleteObject(context.Background(), "42")
+	if apiError, ok := err.(*ApiError); !ok || apiError.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected ApiError with status 503, got %v", err)
+	}
+	if calls.Load() != 4 {
+		t.Errorf("expected 4 calls, got %d", calls.Load())
+	}
+}
+
+func TestDoDoesNotRetryConditionalRequests(t *testing.T) {
+	var calls atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.Header.Get("If-Match") != `"3"` {
+			t.Errorf("expected If-Match \"3\", got %q", r.Header.Get("If-Match"))
+		}
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	err := client.UpdateObject(context.Background(), "42", map[string]interface{}{"name": "db01"}, `"3"`)
+	if apiError, ok := err.(*ApiError); !ok || apiError.StatusCode != http.StatusBadGateway {
+		t.Errorf("expected ApiError with status 502, got %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("expected 1 call, got %d", calls.Load())
+	}
+}
+
+var retryableTests = []struct {
+	method    string
+	status    int
+	retryable bool
+}{
+	{http.MethodGet, http.StatusBadGateway, true},
+	{http.MethodPatch, http.StatusGatewayTimeout, true},
+	{http.MethodDelete, http.StatusServiceUnavailable, true},
+	{http.MethodPost, http.StatusTooManyRequests, true},
+	{http.MethodPost, http.StatusServiceUnavailable, true},
+	{http.MethodPost, http.StatusBadGateway, false},
+	{http.MethodGet, http.StatusBadRequest, false},
+	{http.MethodGet, http.StatusInternalServerError, false},
+}
+
+func TestIsRetryable(t *testing.T) {
+	for _, tt := range retryableTests {
+		t.Run(fmt.Sprintf("%s %d", tt.method, tt.status), func(t *testing.T) {
+			if retryable := isRetryable(tt.method, false, &http.Response{StatusCode: tt.status}); retryable != tt.retryable {
+				t.Errorf("expected %t, got %t", tt.retryable, retryable)
+			}
+		})
+	}
+
+	if !isRetryable(http.MethodGet, false, nil) || isRetryable(http.MethodPost, false, nil) {
+		t.Errorf("expected transport errors to be retried for idempotent methods only")
+	}
+}
+
+func TestIsRetryableConditional(t *testing.T) {
+	for _, status := range []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout} {
+		if isRetryable(http.MethodPatch, true, &http.Response{StatusCode: status}) {
+			t.Errorf("expected conditional request not to be retried on %d", status)
+		}
+	}
+	if isRetryable(http.MethodPatch, true, nil) {
+		t.Errorf("expected conditional request not to be retried on transport errors")
+	}
+	if !isRetryable(http.MethodPatch, true, &http.Response{StatusCode: http.StatusTooManyRequests}) {
+		t.Errorf("expected conditional request to be retried on 429")
+	}
+}
+
+func TestBackoffHonorsRetryAfter(t *testing.T) {
+	client := &AIPEClient{RetryMaxWait: time.Minute}
+
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"7"}}}
+	if wait := client.backoff(0, resp); wait != 7*time.Second {
+		t.Errorf("expected 7s, got %s", wait)
+	}
+
+	resp.Header.Set("Retry-After", "3600")
+	if wait := client.backoff(0, resp); wait != time.Minute {
+		t.Errorf("expected Retry-After to be capped at 1m, got %s", wait)
+	}
+
+	for attempt := 0; attempt < 100; attempt++ {
+		if wait := client.backoff(attempt, nil); wait <= 0 || wait > time.Minute {
+			t.Errorf("expected wait for attempt %d within (0, 1m], got %s", attempt, wait)
+		}
+	}
+}
+
+func TestDoCapsRequestsInFlight(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	// the requests wait until 2 of them are in flight, which proves that the
+	// cap does not serialize them
+	barrier := make(chan struct{})
+	var release sync.Once
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			previous := maxInFlight.Load()
+			if current <= previous || maxInFlight.CompareAndSwap(previous, current) {
+				break
+			}
+		}
+		if current >= 2 {
+			release.Do(func() { close(barrier) })
+		}
+		select {
+		case <-barrier:
+		case <-time.After(5 * time.Second):
+			t.Errorf("expected 2 requests in flight at the same time")
+		}
+		fmt.Fprint(w, `{"dataObject":{}}`)
+	})
+	client.InFlight = semaphore.NewWeighted(2)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.GetObject(context.Background(), "42"); err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if maxInFlight.Load() > 2 {
+		t.Errorf("expected at most 2 requests in flight, got %d", maxInFlight.Load())
+	}
+}
+
+func TestDoLimitsRate(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"dataObject":{}}`)
+	})
+	client.RateLimiter = rate.NewLimiter(rate.Limit(50), 1)
+
+	start := time.Now()
+	for range 6 {
+		if _, err := client.GetObject(context.Background(), "42"); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	// the first request uses the burst, the other five wait 20ms each
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("expected requests to be limited to 50 per second, took %s", elapsed)
+	}
+}
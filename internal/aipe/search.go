@@ -0,0 +1,111 @@
+package aipe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// ObjectQuery describes a search for data objects of one type.
+type ObjectQuery struct {
+	TypeName string
+
+	// Filters restricts the result to objects whose properties have exactly the given values.
+	Filters map[string]string
+
+	// Query is a query expression passed to the AIPE as is.
+	Query string
+
+	// Sort lists the sort orders, each in the form `property` or `property,desc`.
+	Sort []string
+
+	// Limit is the maximum number of objects returned, 0 returns all objects.
+	Limit int
+}
+
+type searchObjectsResponse struct {
+	TotalElements int                      `json:"totalElements"`
+	Objects       []map[string]interface{} `json:"objects"`
+}
+
+// SearchObjects returns all data objects matching the query, following the
+// pagination of the AIPE until all objects or Limit objects have been read.
+func (c *AIPEClient) SearchObjects(ctx context.Context, query ObjectQuery) ([]DataObject, error) {
+	ctx = c.logContext(ctx)
+	objects := []DataObject{}
+	totalElements := 1
+
+	for page := 0; len(objects) < totalElements && (query.Limit == 0 || len(objects) < query.Limit); page++ {
+		params := query.values()
+		params.Set("page", strconv.Itoa(page))
+		objectURL := fmt.Sprintf("%s/data/api/v1/objects?%s", c.URL, params.Encode())
+
+		tflog.SubsystemInfo(ctx, LogSubsystem, "Searching objects", map[string]interface{}{"url": logURL(objectURL), "type": query.TypeName, "filters": query.filterNames(), "page": page})
+		bodyBytes, err := c.do(ctx, http.MethodGet, objectURL, nil, http.StatusOK)
+		if err != nil {
+			return nil, err
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(bodyBytes))
+		decoder.UseNumber()
+
+		var response searchObjectsResponse
+		if err := decoder.Decode(&response); err != nil {
+			return nil, err
+		}
+
+		if page == 0 {
+			totalElements = response.TotalElements
+		}
+		if len(response.Objects) == 0 { // the result changed while paginating
+			break
+		}
+
+		for _, properties := range response.Objects {
+			object, err := newDataObject(properties)
+			if err != nil {
+				return nil, err
+			}
+			objects = append(objects, *object)
+		}
+	}
+
+	if query.Limit > 0 && len(objects) > query.Limit {
+		objects = objects[:query.Limit]
+	}
+	return objects, nil
+}
+
+// filterNames returns the sorted names of the filtered properties.
+func (q ObjectQuery) filterNames() []string {
+	keys := make([]string, 0, len(q.Filters))
+	for k := range q.Filters {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func (q ObjectQuery) values() url.Values {
+	params := url.Values{}
+	params.Set("typeName", q.TypeName)
+
+	for _, k := range q.filterNames() {
+		params.Add("filter", fmt.Sprintf("%s:%s", k, q.Filters[k]))
+	}
+
+	if q.Query != "" {
+		params.Set("query", q.Query)
+	}
+	for _, sort := range q.Sort {
+		params.Add("sort", sort)
+	}
+	return params
+}
@@ -0,0 +1,638 @@
+// Package aipetest provides an in-memory fake of the AIPE data object API and
+// the OIDC token endpoint of its realm, so the provider can be tested without
+// a tenant.
+package aipetest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// LinkDefinition describes a link between two data objects. A link has two
+// relations, one for each side of the link.
+type LinkDefinition struct {
+	Name      string
+	Relations [2]string
+}
+
+// Schema lists the data object types with their properties and the link
+// definitions known to the server.
+type Schema struct {
+	Types map[string][]string
+	Links []LinkDefinition
+}
+
+// DefaultSchema contains the types and links used by the acceptance tests.
+func DefaultSchema() Schema {
+	return Schema{
+		Types: map[string][]string{
+			"test-object": {"foo", "bar"},
+			"server":      {"fqdn", "ip", "cores", "active"},
+			"hoster":      {"name", "support-portal"},
+		},
+		Links: []LinkDefinition{
+			{Name: "server-hosted-by-hoster", Relations: [2]string{"hosted-by", "hosts"}},
+			{Name: "server-backed-up-by-hoster", Relations: [2]string{"backed-up-by", "backs-up"}},
+		},
+	}
+}
+
+// Server is a fake AIPE including the token endpoint of its realm.
+type Server struct {
+	*httptest.Server
+
+	Schema Schema
+
+	// ClientID and ClientSecret are the credentials accepted by the token endpoint.
+	ClientID     string
+	ClientSecret string
+
+	// ClientPublicKey verifies client assertions of the private_key_jwt
+	// authentication. Client assertions are rejected if it is nil.
+	ClientPublicKey crypto.PublicKey
+
+	// Username and Password are the credentials accepted by the password grant.
+	Username string
+	Password string
+
+	// Scopes are the scopes granted if they are requested.
+	Scopes []string
+
+	// Audiences are the audiences tokens can be requested for. The first is
+	// used if no or an unknown audience is requested.
+	Audiences []string
+
+	// TokenLifetime is the lifetime of the issued access tokens.
+	TokenLifetime time.Duration
+
+	// KeepRefreshTokens makes the refresh token grant return no new refresh
+	// token, the used one stays valid instead (RFC 6749 section 6).
+	KeepRefreshTokens bool
+
+	// PageSize is the number of objects returned per page of a list response.
+	// It is small by default to exercise the pagination of the client.
+	PageSize int
+
+	// Now returns the current time and can be replaced to test token expiry.
+	Now func() time.Time
+
+	signingKey []byte
+
+	mutex            sync.Mutex
+	nextID           int
+	objects          map[string]*object
+	links            map[string]map[[2]string]bool
+	tokenRequests    int
+	refreshes        int
+	refreshTokens    map[string]tokenGrant
+	lastTokenRequest url.Values
+	discoveries      int
+}
+
+type object struct {
+	typeName   string
+	properties map[string]interface{}
+	createdAt  time.Time
+	modifiedAt time.Time
+	version    int
+}
+
+// system renders the system block of the object.
+func (o *object) system(id string, clientID string) map[string]interface{} {
+	return map[string]interface{}{
+		"id":         id,
+		"typeName":   o.typeName,
+		"createdAt":  o.createdAt.UTC().Format(time.RFC3339),
+		"createdBy":  clientID,
+		"modifiedAt": o.modifiedAt.UTC().Format(time.RFC3339),
+		"modifiedBy": clientID,
+		"version":    o.version,
+	}
+}
+
+// etag is the entity tag of the current version of the object.
+func (o *object) etag() string {
+	return strconv.Quote(strconv.Itoa(o.version))
+}
+
+// realmPath is the path of the realm on the server.
+const realmPath = "/realms/test"
+
+// tokenPath is the path of the token endpoint relative to the realm.
+const tokenPath = "/protocol/openid-connect/token"
+
+// NewServer starts a server with the [DefaultSchema], which is closed when the test finishes.
+func NewServer(t testing.TB) *Server {
+	s := NewUnstartedServer()
+	s.Start()
+	t.Cleanup(s.Close)
+	return s
+}
+
+// NewUnstartedServer returns a server with the [DefaultSchema] which is not started yet.
+// The caller has to call Start and Close.
+func NewUnstartedServer() *Server {
+	signingKey := make([]byte, 32)
+	if _, err := rand.Read(signingKey); err != nil {
+		panic(err)
+	}
+
+	s := &Server{
+		Schema:        DefaultSchema(),
+		ClientID:      "terraform",
+		ClientSecret:  "secret",
+		Username:      "admin",
+		Password:      "password",
+		Scopes:        []string{"aipe:read", "aipe:write"},
+		Audiences:     []string{"aipe"},
+		TokenLifetime: 5 * time.Minute,
+		PageSize:      2,
+		Now:           time.Now,
+		signingKey:    signingKey,
+		objects:       map[string]*object{},
+		links:         map[string]map[[2]string]bool{},
+		refreshTokens: map[string]tokenGrant{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+realmPath+"/.well-known/openid-configuration", s.handleOpenIDConfiguration)
+	mux.HandleFunc("POST "+realmPath+tokenPath, s.handleToken)
+	mux.Handle("POST /data/api/v1/objects", s.authenticated(s.handleCreateObject))
+	mux.Handle("GET /data/api/v1/objects", s.authenticated(s.handleSearchObjects))
+	mux.Handle("GET /data/api/v1/objects/{id}", s.authenticated(s.handleGetObject))
+	mux.Handle("PATCH /data/api/v1/objects/{id}", s.authenticated(s.handleUpdateObject))
+	mux.Handle("DELETE /data/api/v1/objects/{id}", s.authenticated(s.handleDeleteObject))
+	mux.Handle("GET /data/api/v1/objects/{id}/links", s.authenticated(s.handleGetLinks))
+
+	s.Server = httptest.NewUnstartedServer(mux)
+	return s
+}
+
+// TokenURL is the URL of the token endpoint of the realm.
+func (s *Server) TokenURL() string {
+	return s.RealmURL() + tokenPath
+}
+
+// RealmURL is the URL of the realm, used as authenticator_realm_url.
+func (s *Server) RealmURL() string {
+	return s.URL + realmPath
+}
+
+// TokenRequests returns how many tokens have been issued.
+func (s *Server) TokenRequests() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.tokenRequests
+}
+
+// LastTokenRequest returns the form parameters of the last token request.
+func (s *Server) LastTokenRequest() url.Values {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.lastTokenRequest
+}
+
+// Refreshes returns how many tokens have been issued for a refresh token.
+func (s *Server) Refreshes() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.refreshes
+}
+
+// RevokeRefreshTokens invalidates all refresh tokens issued so far.
+func (s *Server) RevokeRefreshTokens() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	clear(s.refreshTokens)
+}
+
+// Discoveries returns how often the OpenID configuration has been requested.
+func (s *Server) Discoveries() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.discoveries
+}
+
+// Object returns the type name and properties of the object with the ID.
+func (s *Server) Object(id string) (string, map[string]interface{}, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	o, ok := s.objects[id]
+	if !ok {
+		return "", nil, false
+	}
+	return o.typeName, clone(o.properties), true
+}
+
+// Links returns the sorted IDs linked to the object through the relation.
+func (s *Server) Links(id string, linkName string, relationName string) []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	targets, _ := s.linkTargets(id, linkName, relationName)
+	return targets
+}
+
+func (s *Server) now() time.Time {
+	if s.Now == nil {
+		return time.Now()
+	}
+	return s.Now()
+}
+
+func (s *Server) authenticated(handler http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := s.verifyToken(r); err != nil {
+			writeProblem(w, http.StatusUnauthorized, err.Error(), nil)
+			return
+		}
+		handler(w, r)
+	})
+}
+
+type linkRequest struct {
+	LinkName     string   `json:"linkDefinitionName"`
+	RelationName string   `json:"relationName"`
+	Add          []string `json:"add"`
+	Remove       []string `json:"remove"`
+}
+
+type fieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (s *Server) handleCreateObject(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		TypeName   string                 `json:"typeName"`
+		DataObject map[string]interface{} `json:"dataObject"`
+		Links      []linkRequest          `json:"links"`
+	}
+	if !decodeBody(w, r, &request) {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.Schema.Types[request.TypeName]; !ok {
+		writeProblem(w, http.StatusBadRequest, fmt.Sprintf("Unknown type %q", request.TypeName), nil)
+		return
+	}
+	if errors := s.validateProperties(request.TypeName, request.DataObject); len(errors) > 0 {
+		writeProblem(w, http.StatusBadRequest, "Validation failed", errors)
+		return
+	}
+	for _, link := range request.Links {
+		if errors := s.validateLink(link); len(errors) > 0 {
+			writeProblem(w, http.StatusBadRequest, "Validation failed", errors)
+			return
+		}
+	}
+
+	s.nextID++
+	id := strconv.Itoa(s.nextID)
+	s.objects[id] = &object{typeName: request.TypeName, properties: map[string]interface{}{}, createdAt: s.now(), modifiedAt: s.now(), version: 1}
+	setProperties(s.objects[id], request.DataObject)
+	for _, link := range request.Links {
+		for _, target := range link.Add {
+			s.links[link.LinkName][s.linkKey(id, target, link)] = true
+		}
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]string{"dataObjectId": id})
+}
+
+func (s *Server) handleGetObject(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	id := r.PathValue("id")
+	o, ok := s.objects[id]
+	if !ok {
+		writeProblem(w, http.StatusNotFound, fmt.Sprintf("Data object %s not found", id), nil)
+		return
+	}
+
+	dataObject := clone(o.properties)
+	dataObject["system"] = o.system(id, s.ClientID)
+	w.Header().Set("ETag", o.etag())
+	writeJSON(w, http.StatusOK, map[string]interface{}{"dataObject": dataObject})
+}
+
+// handleSearchObjects supports exact property filters and sorting, but no query expressions.
+func (s *Server) handleSearchObjects(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	query := r.URL.Query()
+	typeName := query.Get("typeName")
+	if _, ok := s.Schema.Types[typeName]; !ok {
+		writeProblem(w, http.StatusBadRequest, fmt.Sprintf("Unknown type %q", typeName), nil)
+		return
+	}
+	if query.Has("query") {
+		writeProblem(w, http.StatusBadRequest, "Query expressions are not supported by aipetest", nil)
+		return
+	}
+
+	filters := map[string]string{}
+	for _, filter := range query["filter"] {
+		property, value, ok := strings.Cut(filter, ":")
+		if !ok {
+			writeProblem(w, http.StatusBadRequest, fmt.Sprintf("Malformed filter %q", filter), nil)
+			return
+		}
+		filters[property] = value
+	}
+
+	var ids []string
+	for id, o := range s.objects {
+		if o.typeName == typeName && matches(o, filters) {
+			ids = append(ids, id)
+		}
+	}
+	slices.SortFunc(ids, func(a, b string) int { return s.compareObjects(a, b, query["sort"]) })
+
+	page, _ := strconv.Atoi(query.Get("page"))
+	objects := []map[string]interface{}{}
+	for _, id := range paginate(ids, page, s.PageSize) {
+		dataObject := clone(s.objects[id].properties)
+		dataObject["system"] = s.objects[id].system(id, s.ClientID)
+		objects = append(objects, dataObject)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"totalElements": len(ids),
+		"objects":       objects,
+	})
+}
+
+func matches(o *object, filters map[string]string) bool {
+	for property, value := range filters {
+		v, ok := o.properties[property]
+		if !ok || fmt.Sprint(v) != value {
+			return false
+		}
+	}
+	return true
+}
+
+// compareObjects orders by the sort properties and falls back to the numeric ID.
+func (s *Server) compareObjects(a string, b string, sorts []string) int {
+	for _, sort := range sorts {
+		property, direction, _ := strings.Cut(sort, ",")
+		result := strings.Compare(fmt.Sprint(s.objects[a].properties[property]), fmt.Sprint(s.objects[b].properties[property]))
+		if direction == "desc" {
+			result = -result
+		}
+		if result != 0 {
+			return result
+		}
+	}
+
+	idA, _ := strconv.Atoi(a)
+	idB, _ := strconv.Atoi(b)
+	return idA - idB
+}
+
+func (s *Server) handleUpdateObject(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		DataObject map[string]interface{} `json:"dataObject"`
+		Links      []linkRequest          `json:"links"`
+	}
+	if !decodeBody(w, r, &request) {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	id := r.PathValue("id")
+	o, ok := s.objects[id]
+	if !ok {
+		writeProblem(w, http.StatusNotFound, fmt.Sprintf("Data object %s not found", id), nil)
+		return
+	}
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && ifMatch != o.etag() {
+		writeProblem(w, http.StatusPreconditionFailed, fmt.Sprintf("Data object %s has been modified", id), nil)
+		return
+	}
+	if errors := s.validateProperties(o.typeName, request.DataObject); len(errors) > 0 {
+		writeProblem(w, http.StatusBadRequest, "Validation failed", errors)
+		return
+	}
+	for _, link := range request.Links {
+		if errors := s.validateLink(link); len(errors) > 0 {
+			writeProblem(w, http.StatusBadRequest, "Validation failed", errors)
+			return
+		}
+	}
+
+	if len(request.DataObject) > 0 {
+		setProperties(o, request.DataObject)
+		o.modifiedAt = s.now()
+		o.version++
+	}
+	for _, link := range request.Links {
+		for _, target := range link.Add {
+			s.links[link.LinkName][s.linkKey(id, target, link)] = true
+		}
+		for _, target := range link.Remove {
+			delete(s.links[link.LinkName], s.linkKey(id, target, link))
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleDeleteObject(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	id := r.PathValue("id")
+	if _, ok := s.objects[id]; !ok {
+		writeProblem(w, http.StatusNotFound, fmt.Sprintf("Data object %s not found", id), nil)
+		return
+	}
+
+	delete(s.objects, id)
+	for _, pairs := range s.links {
+		for pair := range pairs {
+			if pair[0] == id || pair[1] == id {
+				delete(pairs, pair)
+			}
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleGetLinks(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	id := r.PathValue("id")
+	if _, ok := s.objects[id]; !ok {
+		writeProblem(w, http.StatusNotFound, fmt.Sprintf("Data object %s not found", id), nil)
+		return
+	}
+
+	query := r.URL.Query()
+	targets, ok := s.linkTargets(id, query.Get("linkDefinitionName"), query.Get("relationName"))
+	if !ok {
+		writeProblem(w, http.StatusBadRequest, "Unknown link definition or relation", nil)
+		return
+	}
+
+	page, _ := strconv.Atoi(query.Get("page"))
+	objects := []map[string]interface{}{}
+	for _, target := range paginate(targets, page, s.PageSize) {
+		objects = append(objects, map[string]interface{}{"system": map[string]string{"id": target}})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"totalElements": len(targets),
+		"objects":       objects,
+	})
+}
+
+func (s *Server) validateProperties(typeName string, properties map[string]interface{}) []fieldError {
+	var errors []fieldError
+	for k := range properties {
+		if !slices.Contains(s.Schema.Types[typeName], k) {
+			errors = append(errors, fieldError{Field: "dataObject." + k, Message: fmt.Sprintf("Unknown property for type %s", typeName)})
+		}
+	}
+	slices.SortFunc(errors, func(a, b fieldError) int { return strings.Compare(a.Field, b.Field) })
+	return errors
+}
+
+func (s *Server) validateLink(link linkRequest) []fieldError {
+	definition, ok := s.linkDefinition(link.LinkName)
+	if !ok || !slices.Contains(definition.Relations[:], link.RelationName) {
+		return []fieldError{{Field: "links", Message: fmt.Sprintf("Unknown link %s with relation %s", link.LinkName, link.RelationName)}}
+	}
+
+	var errors []fieldError
+	for _, target := range append(slices.Clone(link.Add), link.Remove...) {
+		if _, ok := s.objects[target]; !ok {
+			errors = append(errors, fieldError{Field: "links", Message: fmt.Sprintf("Data object %s not found", target)})
+		}
+	}
+	return errors
+}
+
+func (s *Server) linkDefinition(name string) (LinkDefinition, bool) {
+	for _, definition := range s.Schema.Links {
+		if definition.Name == name {
+			if s.links[name] == nil {
+				s.links[name] = map[[2]string]bool{}
+			}
+			return definition, true
+		}
+	}
+	return LinkDefinition{}, false
+}
+
+// linkKey stores links in the order of the relations of the link definition,
+// so both sides of a link read the same pair.
+func (s *Server) linkKey(source string, target string, link linkRequest) [2]string {
+	definition, _ := s.linkDefinition(link.LinkName)
+	if link.RelationName == definition.Relations[0] {
+		return [2]string{source, target}
+	}
+	return [2]string{target, source}
+}
+
+func (s *Server) linkTargets(id string, linkName string, relationName string) ([]string, bool) {
+	definition, ok := s.linkDefinition(linkName)
+	if !ok || !slices.Contains(definition.Relations[:], relationName) {
+		return nil, false
+	}
+
+	sourceSide := 0
+	if relationName == definition.Relations[1] {
+		sourceSide = 1
+	}
+
+	targets := []string{}
+	for pair := range s.links[linkName] {
+		if pair[sourceSide] == id {
+			targets = append(targets, pair[1-sourceSide])
+		}
+	}
+	slices.Sort(targets)
+	return targets, true
+}
+
+func setProperties(o *object, properties map[string]interface{}) {
+	for k, v := range properties {
+		if v == nil {
+			delete(o.properties, k)
+		} else {
+			o.properties[k] = v
+		}
+	}
+}
+
+func paginate(ids []string, page int, pageSize int) []string {
+	if pageSize <= 0 {
+		return ids
+	}
+	start := min(page*pageSize, len(ids))
+	end := min(start+pageSize, len(ids))
+	return ids[start:end]
+}
+
+func clone(properties map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(properties))
+	for k, v := range properties {
+		result[k] = v
+	}
+	return result
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		writeProblem(w, http.StatusBadRequest, fmt.Sprintf("Malformed request body: %s", err), nil)
+		return false
+	}
+	return true
+}
+
+func writeProblem(w http.ResponseWriter, status int, detail string, errors []fieldError) {
+	problem := map[string]interface{}{
+		"type":   "about:blank",
+		"title":  http.StatusText(status),
+		"status": status,
+		"detail": detail,
+	}
+	if len(errors) > 0 {
+		problem["errors"] = errors
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,346 @@
+package aipetest_test
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Serviceware/terraform-provider-swp/internal/aipe"
+	"github.com/Serviceware/terraform-provider-swp/internal/aipetest"
+	"github.com/Serviceware/terraform-provider-swp/internal/authenticator"
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+)
+
+func newClient(server *aipetest.Server) *aipe.AIPEClient {
+	return &aipe.AIPEClient{
+		HTTPClient: server.Client(),
+		URL:        server.URL,
+		Authenticator: &authenticator.AuthenticatorClient{
+			Client:              server.Client(),
+			ApplicationUsername: server.ClientID,
+			ApplicationPassword: server.ClientSecret,
+			URL:                 server.RealmURL(),
+		},
+	}
+}
+
+func TestObjectLifecycle(t *testing.T) {
+	server := aipetest.NewServer(t)
+	client := newClient(server)
+	ctx := context.Background()
+
+	id, err := client.CreateObject(ctx, "server", map[string]interface{}{"fqdn": "db01", "active": true})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := client.UpdateObject(ctx, id, map[string]interface{}{"fqdn": "db02", "active": nil}, ""); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	object, err := client.GetObject(ctx, id)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(object.Properties) != 1 || object.Properties["fqdn"] != "db02" {
+		t.Errorf("unexpected object %v", object.Properties)
+	}
+	if object.ID != id || object.System.TypeName != "server" {
+		t.Errorf("unexpected system metadata %+v", object.System)
+	}
+
+	if err := client.DeleteObject(ctx, id); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := client.GetObject(ctx, id); !aipe.ErrorIsNotFound(err) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
+
+func TestStaleUpdateIsRejected(t *testing.T) {
+	server := aipetest.NewServer(t)
+	client := newClient(server)
+	ctx := context.Background()
+
+	id, err := client.CreateObject(ctx, "test-object", map[string]interface{}{"foo": "1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	object, err := client.GetObject(ctx, id)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := client.UpdateObject(ctx, id, map[string]interface{}{"foo": "2"}, object.ETag); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	err = client.UpdateObject(ctx, id, map[string]interface{}{"foo": "3"}, object.ETag)
+	if !aipe.ErrorIsConflict(err) {
+		t.Fatalf("expected conflict, got %v", err)
+	}
+
+	object, err = client.GetObject(ctx, id)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if object.Properties["foo"] != "2" {
+		t.Errorf("expected stale update to be discarded, got %v", object.Properties)
+	}
+}
+
+func TestUnknownPropertyIsRejected(t *testing.T) {
+	server := aipetest.NewServer(t)
+	client := newClient(server)
+
+	_, err := client.CreateObject(context.Background(), "test-object", map[string]interface{}{"oof": "bar"})
+
+	apiError, ok := err.(*aipe.ApiError)
+	if !ok || apiError.StatusCode != 400 {
+		t.Fatalf("expected ApiError with status 400, got %v", err)
+	}
+	if len(apiError.FieldErrors) != 1 || apiError.FieldErrors[0].Field != "oof" {
+		t.Errorf("expected field error for oof, got %+v", apiError.FieldErrors)
+	}
+}
+
+func TestLinksArePaginatedAndSymmetric(t *testing.T) {
+	server := aipetest.NewServer(t)
+	client := newClient(server)
+	ctx := context.Background()
+
+	hoster, _ := client.CreateObject(ctx, "hoster", map[string]interface{}{"name": "Cloud Inc."})
+	var servers []string
+	for _, fqdn := range []string{"db01", "db02", "db03"} {
+		id, err := client.CreateObject(ctx, "server", map[string]interface{}{"fqdn": fqdn})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		servers = append(servers, id)
+	}
+
+	if err := client.UpdateDataObjectLinks(ctx, hoster, "server-hosted-by-hoster", "hosts", servers, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := client.UpdateDataObjectLinks(ctx, hoster, "server-hosted-by-hoster", "hosts", nil, servers[:1]); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	targets, err := client.GetDataObjectLinks(ctx, hoster, "server-hosted-by-hoster", "hosts")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !slices.Equal(targets, servers[1:]) {
+		t.Errorf("expected %v, got %v", servers[1:], targets)
+	}
+
+	backwards, err := client.GetDataObjectLinks(ctx, servers[2], "server-hosted-by-hoster", "hosted-by")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !slices.Equal(backwards, []string{hoster}) {
+		t.Errorf("expected %v, got %v", []string{hoster}, backwards)
+	}
+}
+
+func TestLinkBatchIsAtomic(t *testing.T) {
+	server := aipetest.NewServer(t)
+	client := newClient(server)
+	ctx := context.Background()
+
+	hoster, _ := client.CreateObject(ctx, "hoster", map[string]interface{}{"name": "Cloud Inc."})
+	db01, _ := client.CreateObject(ctx, "server", map[string]interface{}{"fqdn": "db01"})
+
+	err := client.UpdateDataObjectLinksBatch(ctx, hoster, []aipe.LinkDefinition{
+		{LinkName: "server-hosted-by-hoster", RelationName: "hosts", Add: []string{db01}},
+		{LinkName: "server-backed-up-by-hoster", RelationName: "backs-up", Add: []string{"missing"}},
+	})
+	if err == nil {
+		t.Fatalf("expected error for missing target")
+	}
+	if links := server.Links(hoster, "server-hosted-by-hoster", "hosts"); len(links) != 0 {
+		t.Errorf("expected no links after failed batch, got %v", links)
+	}
+
+	err = client.UpdateDataObjectLinksBatch(ctx, hoster, []aipe.LinkDefinition{
+		{LinkName: "server-hosted-by-hoster", RelationName: "hosts", Add: []string{db01}},
+		{LinkName: "server-backed-up-by-hoster", RelationName: "backs-up", Add: []string{db01}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, link := range [][2]string{{"server-hosted-by-hoster", "hosts"}, {"server-backed-up-by-hoster", "backs-up"}} {
+		if links := server.Links(hoster, link[0], link[1]); !slices.Equal(links, []string{db01}) {
+			t.Errorf("expected %s to link %v, got %v", link[1], []string{db01}, links)
+		}
+	}
+}
+
+func TestObjectIsCreatedWithLinks(t *testing.T) {
+	server := aipetest.NewServer(t)
+	client := newClient(server)
+	ctx := context.Background()
+
+	db01, _ := client.CreateObject(ctx, "server", map[string]interface{}{"fqdn": "db01"})
+	db02, _ := client.CreateObject(ctx, "server", map[string]interface{}{"fqdn": "db02"})
+
+	hoster, err := client.CreateObjectWithLinks(ctx, "hoster", map[string]interface{}{"name": "Cloud Inc."}, []aipe.LinkDefinition{
+		{LinkName: "server-hosted-by-hoster", RelationName: "hosts", Add: []string{db01}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if links := server.Links(hoster, "server-hosted-by-hoster", "hosts"); !slices.Equal(links, []string{db01}) {
+		t.Errorf("expected %v, got %v", []string{db01}, links)
+	}
+
+	err = client.UpdateObjectWithLinks(ctx, hoster, map[string]interface{}{"name": "Cloud Corp."}, []aipe.LinkDefinition{
+		{LinkName: "server-hosted-by-hoster", RelationName: "hosts", Add: []string{db02}, Remove: []string{db01}},
+	}, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if links := server.Links(hoster, "server-hosted-by-hoster", "hosts"); !slices.Equal(links, []string{db02}) {
+		t.Errorf("expected %v, got %v", []string{db02}, links)
+	}
+
+	if _, err := client.CreateObjectWithLinks(ctx, "hoster", nil, []aipe.LinkDefinition{
+		{LinkName: "server-hosted-by-hoster", RelationName: "hosts", Add: []string{"missing"}},
+	}); err == nil {
+		t.Errorf("expected error for missing target")
+	}
+	if _, _, ok := server.Object("4"); ok {
+		t.Errorf("expected rejected object not to be created")
+	}
+}
+
+func TestExpiredTokensAreRejected(t *testing.T) {
+	server := aipetest.NewServer(t)
+	client := newClient(server)
+	ctx := context.Background()
+
+	now := time.Now()
+	server.Now = func() time.Time { return now }
+
+	id, err := client.CreateObject(ctx, "test-object", map[string]interface{}{"foo": "bar"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The token issued above has expired for the server, but the client
+	// still considers it valid until its exp claim.
+	now = now.Add(server.TokenLifetime)
+	if _, err := client.GetObject(ctx, id); err == nil {
+		t.Errorf("expected expired token to be rejected")
+	}
+
+	if server.TokenRequests() != 1 {
+		t.Errorf("expected 1 token request, got %d", server.TokenRequests())
+	}
+}
+
+func TestInvalidClientCredentials(t *testing.T) {
+	server := aipetest.NewServer(t)
+	client := newClient(server)
+	client.Authenticator.ApplicationPassword = "wrong"
+
+	_, err := client.GetObject(context.Background(), "1")
+
+	authError, ok := err.(*authenticator.AuthError)
+	if !ok || authError.StatusCode != 401 || authError.Code != "invalid_client" {
+		t.Fatalf("expected AuthError invalid_client, got %v", err)
+	}
+}
+
+func TestSearchObjects(t *testing.T) {
+	server := aipetest.NewServer(t)
+	client := newClient(server)
+	ctx := context.Background()
+
+	ids := map[string]string{}
+	for _, fqdn := range []string{"db02", "db01", "web01", "db03"} {
+		id, err := client.CreateObject(ctx, "server", map[string]interface{}{"fqdn": fqdn, "active": fqdn != "db03"})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		ids[fqdn] = id
+	}
+
+	objects, err := client.SearchObjects(ctx, aipe.ObjectQuery{
+		TypeName: "server",
+		Filters:  map[string]string{"active": "true"},
+		Sort:     []string{"fqdn,desc"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var found []string
+	for _, object := range objects {
+		found = append(found, object.Properties["fqdn"].(string))
+		if object.ID != ids[object.Properties["fqdn"].(string)] {
+			t.Errorf("unexpected id %s for %v", object.ID, object.Properties)
+		}
+	}
+	if expected := []string{"web01", "db02", "db01"}; !slices.Equal(found, expected) {
+		t.Errorf("expected %v, got %v", expected, found)
+	}
+
+	limited, err := client.SearchObjects(ctx, aipe.ObjectQuery{TypeName: "server", Sort: []string{"fqdn"}, Limit: 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(limited) != 3 || limited[0].ID != ids["db01"] {
+		t.Errorf("unexpected limited result %v", limited)
+	}
+}
+
+func TestLogsAreRedacted(t *testing.T) {
+	server := aipetest.NewServer(t)
+	server.ClientSecret = "client-secret-for-TestLogsAreRedacted"
+	client := newClient(server)
+	client.SensitiveProperties = []string{"fqdn"}
+
+	var output bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &output)
+
+	id, err := client.CreateObject(ctx, "server", map[string]interface{}{"fqdn": "db01.example.com", "active": true})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := client.GetObject(ctx, id); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := client.SearchObjects(ctx, aipe.ObjectQuery{TypeName: "server", Filters: map[string]string{"fqdn": "db01.example.com"}}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := client.CreateObject(ctx, "server", map[string]interface{}{"fqdn": "db01.example.com", "unknown": "x"}); err == nil {
+		t.Fatalf("expected error for unknown property")
+	}
+	if _, err := client.GetDataObjectLinks(ctx, id, "server-hosted-by-hoster", "hosted-by"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := client.UpdateDataObjectLinks(ctx, id, "server-hosted-by-hoster", "hosted-by", []string{"missing-db01.example.com"}, nil); err == nil {
+		t.Fatalf("expected error for missing link target")
+	}
+
+	discovery := newClient(server).Authenticator
+	discovery.URL = server.URL + "/realms/unknown"
+	if _, err := discovery.Authenticate(ctx); err == nil {
+		t.Fatalf("expected discovery to fail")
+	}
+
+	logs := output.String()
+	for _, secret := range []string{"db01.example.com", server.ClientSecret, client.Authenticator.Token, "linkDefinitionName=", "404 page not found"} {
+		if strings.Contains(logs, secret) {
+			t.Errorf("expected %q to be masked in logs:\n%s", secret, logs)
+		}
+	}
+	for _, expected := range []string{`"@module":"provider.aipe"`, `"@module":"provider.authenticator"`, `"property.fqdn":"***"`, `"property.active":true`, `"filters":["fqdn"]`, `"status":400`, `"status":404`, `"links":1`} {
+		if !strings.Contains(logs, expected) {
+			t.Errorf("expected %s in logs:\n%s", expected, logs)
+		}
+	}
+}
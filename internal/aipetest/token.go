@@ -0,0 +1,293 @@
+package aipetest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// handleOpenIDConfiguration serves the discovery document of the realm.
+func (s *Server) handleOpenIDConfiguration(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	s.discoveries++
+	s.mutex.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                s.RealmURL(),
+		"token_endpoint":                        s.TokenURL(),
+		"grant_types_supported":                 []string{"client_credentials", "password"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_post", "client_secret_basic", "private_key_jwt"},
+	})
+}
+
+// handleToken implements the client credentials, password and refresh token
+// grants of the token endpoint. Clients authenticate with their secret in the form body or
+// basic authentication header, or with a private_key_jwt client assertion.
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	grantType := r.PostForm.Get("grant_type")
+	if grantType != "client_credentials" && grantType != "password" && grantType != "refresh_token" {
+		writeOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", "Unsupported grant_type")
+		return
+	}
+
+	if err := s.authenticateClient(r, grantType); err != nil {
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_client", err.Error())
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.lastTokenRequest = r.PostForm
+
+	grant := tokenGrant{subject: s.ClientID, scope: s.grantedScope(r.PostForm.Get("scope")), audience: s.audience(r.PostForm.Get("audience"))}
+	switch grantType {
+	case "password":
+		if r.PostForm.Get("username") != s.Username || r.PostForm.Get("password") != s.Password {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "Invalid user credentials")
+			return
+		}
+		grant.subject = s.Username
+	case "refresh_token":
+		var ok bool
+		grant, ok = s.refreshTokens[r.PostForm.Get("refresh_token")]
+		if !ok {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "Invalid refresh token")
+			return
+		}
+		s.refreshes++
+	}
+
+	s.tokenRequests++
+
+	token, err := s.issueToken(grant)
+	if err != nil {
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+
+	response := map[string]interface{}{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_in":   int(s.TokenLifetime.Seconds()),
+	}
+	if grantType != "refresh_token" || !s.KeepRefreshTokens {
+		delete(s.refreshTokens, r.PostForm.Get("refresh_token"))
+		refreshToken := rand.Text()
+		s.refreshTokens[refreshToken] = grant
+		response["refresh_token"] = refreshToken
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// authenticateClient checks the client authentication of a token request. The
+// password and refresh token grants may omit the client secret, like a public
+// client.
+func (s *Server) authenticateClient(r *http.Request, grantType string) error {
+	if assertion := r.PostForm.Get("client_assertion"); assertion != "" {
+		if r.PostForm.Get("client_assertion_type") != "urn:ietf:params:oauth:client-assertion-type:jwt-bearer" {
+			return fmt.Errorf("Unsupported client_assertion_type")
+		}
+		return s.verifyClientAssertion(assertion)
+	}
+
+	clientID, clientSecret, basic := r.BasicAuth()
+	if basic {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID = r.PostForm.Get("client_id")
+		clientSecret = r.PostForm.Get("client_secret")
+	}
+
+	if clientID != s.ClientID {
+		return fmt.Errorf("Invalid client or Invalid client credentials")
+	}
+	if grantType != "client_credentials" && !basic && !r.PostForm.Has("client_secret") {
+		return nil
+	}
+	if clientSecret != s.ClientSecret {
+		return fmt.Errorf("Invalid client or Invalid client credentials")
+	}
+	return nil
+}
+
+// verifyClientAssertion checks signature, subject, audience and expiry of a
+// client assertion signed with the key of ClientPublicKey.
+func (s *Server) verifyClientAssertion(assertion string) error {
+	if s.ClientPublicKey == nil {
+		return fmt.Errorf("Client assertions are not configured")
+	}
+
+	parts := strings.Split(assertion, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("Malformed client assertion")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return fmt.Errorf("Malformed client assertion signature")
+	}
+	if !verifySignature(s.ClientPublicKey, []byte(parts[0]+"."+parts[1]), signature) {
+		return fmt.Errorf("Invalid client assertion signature")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return fmt.Errorf("Malformed client assertion payload")
+	}
+	var claims struct {
+		Iss string `json:"iss"`
+		Sub string `json:"sub"`
+		Aud string `json:"aud"`
+		Exp int64  `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return fmt.Errorf("Malformed client assertion payload")
+	}
+
+	if claims.Iss != s.ClientID || claims.Sub != s.ClientID {
+		return fmt.Errorf("Invalid client or Invalid client credentials")
+	}
+	if claims.Aud != s.TokenURL() && claims.Aud != s.RealmURL() {
+		return fmt.Errorf("Invalid client assertion audience %q", claims.Aud)
+	}
+	if !s.now().Before(time.Unix(claims.Exp, 0)) {
+		return fmt.Errorf("Client assertion expired")
+	}
+	return nil
+}
+
+// verifySignature verifies RS256, ES256 and EdDSA signatures.
+func verifySignature(publicKey crypto.PublicKey, data []byte, signature []byte) bool {
+	digest := sha256.Sum256(data)
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	case *ecdsa.PublicKey:
+		if len(signature) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(key, digest[:], r, s)
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, data, signature)
+	}
+	return false
+}
+
+// tokenGrant is what a token has been issued for, kept with the refresh token.
+type tokenGrant struct {
+	subject  string
+	scope    string
+	audience string
+}
+
+// grantedScope returns the requested scopes known to the server. Unknown
+// scopes are dropped instead of failing the request, like Keycloak does.
+func (s *Server) grantedScope(requested string) string {
+	var granted []string
+	for _, scope := range strings.Fields(requested) {
+		if slices.Contains(s.Scopes, scope) {
+			granted = append(granted, scope)
+		}
+	}
+	return strings.Join(granted, " ")
+}
+
+// audience returns the requested audience if it is known to the server and
+// the default audience otherwise.
+func (s *Server) audience(requested string) string {
+	if requested != "" && slices.Contains(s.Audiences, requested) {
+		return requested
+	}
+	if len(s.Audiences) == 0 {
+		return ""
+	}
+	return s.Audiences[0]
+}
+
+// issueToken creates an HS256 signed JWT for the grant.
+func (s *Server) issueToken(grant tokenGrant) (string, error) {
+	now := s.now()
+	header, err := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   s.RealmURL(),
+		"sub":   grant.subject,
+		"azp":   grant.subject,
+		"aud":   grant.audience,
+		"scope": grant.scope,
+		"iat":   now.Unix(),
+		"exp":   now.Add(s.TokenLifetime).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	return unsigned + "." + s.sign(unsigned), nil
+}
+
+func (s *Server) sign(unsigned string) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifyToken checks signature and expiration of the bearer token of the request.
+func (s *Server) verifyToken(r *http.Request) error {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return fmt.Errorf("missing bearer token")
+	}
+
+	lastDot := strings.LastIndex(token, ".")
+	if lastDot < 0 || !hmac.Equal([]byte(s.sign(token[:lastDot])), []byte(token[lastDot+1:])) {
+		return fmt.Errorf("invalid token signature")
+	}
+
+	_, payloadPart, _ := strings.Cut(token[:lastDot], ".")
+	payload, err := base64.RawURLEncoding.DecodeString(payloadPart)
+	if err != nil {
+		return err
+	}
+
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return err
+	}
+	if !s.now().Before(time.Unix(claims.Exp, 0)) {
+		return fmt.Errorf("token expired")
+	}
+	return nil
+}
+
+func writeOAuthError(w http.ResponseWriter, status int, code string, description string) {
+	writeJSON(w, status, map[string]string{
+		"error":             code,
+		"error_description": description,
+	})
+}
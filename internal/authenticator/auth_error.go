@@ -0,0 +1,67 @@
+package authenticator
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// AuthError is a token request rejected by the token endpoint, with the
+// error response of RFC 6749 section 5.2 if the body contained one.
+type AuthError struct {
+	StatusCode int
+
+	// Code, Description and URI are the error, error_description and error_uri of the response.
+	Code        string
+	Description string
+	URI         string
+}
+
+func (e *AuthError) Error() string {
+	switch {
+	case e.Code == "":
+		return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+	case e.Description == "":
+		return e.Code
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Description)
+}
+
+// ClaimError is a token issued by the token endpoint whose scope or aud claim
+// does not contain the scopes or audience requested by the client.
+type ClaimError struct {
+	// Claim is the checked claim, scope or aud.
+	Claim string
+
+	// Missing lists the requested values the token has not been issued for,
+	// Granted the values of the claim.
+	Missing []string
+	Granted []string
+}
+
+func (e *ClaimError) Error() string {
+	if e.Claim == "aud" {
+		return fmt.Sprintf("token has not been issued for audience %s, got: %s", strings.Join(e.Missing, " "), strings.Join(e.Granted, " "))
+	}
+	return fmt.Sprintf("token has not been granted the requested scopes %s, got: %s", strings.Join(e.Missing, " "), strings.Join(e.Granted, " "))
+}
+
+// newAuthError creates an [AuthError] for an unexpected response. Bodies
+// which are no OAuth error response only result in a less detailed error.
+func newAuthError(statusCode int, body []byte) *AuthError {
+	authError := &AuthError{StatusCode: statusCode}
+
+	var response struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+		ErrorURI         string `json:"error_uri"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return authError
+	}
+
+	authError.Code = response.Error
+	authError.Description = response.ErrorDescription
+	authError.URI = response.ErrorURI
+	return authError
+}
@@ -0,0 +1,31 @@
+package authenticator
+
+import "testing"
+
+func TestNewAuthError(t *testing.T) {
+	for name, tc := range map[string]struct {
+		body     string
+		expected string
+	}{
+		"error with description": {
+			body:     `{"error":"invalid_client","error_description":"Invalid client secret"}`,
+			expected: "invalid_client: Invalid client secret",
+		},
+		"error only": {
+			body:     `{"error":"invalid_grant"}`,
+			expected: "invalid_grant",
+		},
+		"no OAuth error": {
+			body:     `<html>Bad Gateway</html>`,
+			expected: "unexpected status code: 401",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			authError := newAuthError(401, []byte(tc.body))
+
+			if authError.StatusCode != 401 || authError.Error() != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, authError.Error())
+			}
+		})
+	}
+}
@@ -2,77 +2,429 @@ package authenticator
 
 import (
 	"context"
+	"crypto"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"golang.org/x/sync/singleflight"
 )
 
+// AuthMethod selects how the client authenticates at the token endpoint.
+type AuthMethod string
+
+const (
+	// AuthMethodClientSecretPost sends client_id and client_secret in the form body
+	// of a client credentials grant. It is used if no method is set.
+	AuthMethodClientSecretPost AuthMethod = "client_secret_post"
+
+	// AuthMethodClientSecretBasic sends client_id and client_secret as HTTP basic
+	// authentication header of a client credentials grant.
+	AuthMethodClientSecretBasic AuthMethod = "client_secret_basic"
+
+	// AuthMethodPrivateKeyJWT authenticates a client credentials grant with a
+	// JWT client assertion signed with PrivateKey (RFC 7523).
+	AuthMethodPrivateKeyJWT AuthMethod = "private_key_jwt"
+
+	// AuthMethodPassword uses the resource owner password grant with Username and
+	// Password. The client secret is only sent if it is set.
+	AuthMethodPassword AuthMethod = "password"
+)
+
+// DefaultRefreshFraction is the fraction of the token lifetime after which
+// the token is refreshed in the background.
+const DefaultRefreshFraction = 0.75
+
+// DefaultRefreshRetryInterval is the time to wait after a failed background
+// refresh before the next one is started.
+const DefaultRefreshRetryInterval = 30 * time.Second
+
+// tokenKey is the singleflight key of fetching a token.
+const tokenKey = "token"
+
+// ReservedParams are the token request parameters set by the client itself.
+var ReservedParams = []string{
+	"grant_type", "client_id", "client_secret", "client_assertion_type", "client_assertion",
+	"username", "password", "refresh_token", "scope", "audience",
+}
+
+// AuthMethods lists all supported values of [AuthMethod].
+var AuthMethods = []AuthMethod{AuthMethodClientSecretPost, AuthMethodClientSecretBasic, AuthMethodPrivateKeyJWT, AuthMethodPassword}
+
 type AuthenticatorClient struct {
 	Client *http.Client
 
+	AuthMethod AuthMethod
+
+	// ApplicationUsername and ApplicationPassword are the client ID and client secret.
 	ApplicationUsername string
 	ApplicationPassword string
 
-	URL string
+	// PrivateKey signs the client assertion of [AuthMethodPrivateKeyJWT] and
+	// KeyID is sent as its kid header, if set.
+	PrivateKey crypto.Signer
+	KeyID      string
+
+	// Username and Password are the resource owner credentials of [AuthMethodPassword].
+	Username string
+	Password string
+
+	// Scopes and Audience are requested with the token and checked against
+	// the scope and aud claims of the returned token, if it is a JWT.
+	Scopes   []string
+	Audience string
+
+	// ExtraParams are additional parameters of the token request. They must
+	// not contain the parameters set by the client, see [ReservedParams].
+	ExtraParams map[string]string
+
+	// URL is the issuer, the token endpoint is discovered from its OpenID
+	// configuration unless TokenURL is set.
+	URL      string
+	TokenURL string
+
+	tokenEndpoint string
+
+	// AccessToken is a pre-issued token which is used as is instead of
+	// requesting tokens from the token endpoint.
+	AccessToken string
+
+	// CredentialCommand is a command and its arguments printing a token as
+	// JSON, see [CredentialCommandOutput]. It is run instead of requesting
+	// tokens from the token endpoint, whenever the cached token is about to
+	// expire.
+	CredentialCommand []string
+
+	// RefreshFraction is the fraction of the token lifetime after which the
+	// token is refreshed in the background, while the cached token is still
+	// used. Defaults to [DefaultRefreshFraction].
+	RefreshFraction float64
+
+	// RefreshRetryInterval is the time to wait after a failed background
+	// refresh before the next one is started, the cached token is used
+	// meanwhile. Defaults to [DefaultRefreshRetryInterval].
+	RefreshRetryInterval time.Duration
+
+	// Token is the cached token, its refresh token and lifetime are kept
+	// alongside, as well as the time of the last failed background refresh.
+	// TokenMutex guards them, but is never held during requests.
+	Token           string
+	refreshToken    string
+	tokenIssuedAt   time.Time
+	tokenExpiresAt  time.Time
+	refreshFailedAt time.Time
+	TokenMutex      sync.Mutex
+
+	// fetches makes concurrent callers wait for a single token request.
+	fetches singleflight.Group
+}
+
+// token is a token returned by the token endpoint or the credential command.
+type token struct {
+	accessToken  string
+	refreshToken string
+	expiresAt    time.Time
+}
 
-	Token      string
-	TokenMutex sync.Mutex
+// tokenResponse is the successful response of the token endpoint.
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
 }
 
 func (c *AuthenticatorClient) Authenticate(ctx context.Context) (string, error) {
-	c.TokenMutex.Lock()
-	defer c.TokenMutex.Unlock()
+	if c.AccessToken != "" {
+		if _, err := ExpirationTime(c.AccessToken); err == nil && !IsValidIn(c.AccessToken, 0) {
+			return "", fmt.Errorf("the access token has expired")
+		}
+		return c.AccessToken, nil
+	}
 
+	c.TokenMutex.Lock()
+	cached := c.Token
 	// Only return cached token if it is valid for at least 5 seconds
-	if c.Token != "" && IsValidIn(c.Token, 5*time.Second) {
-		return c.Token, nil
+	valid := cached != "" && c.isValidIn(5*time.Second)
+	due := valid && c.refreshIsDue()
+	c.TokenMutex.Unlock()
+
+	// The token request must not be cancelled with the context of the first
+	// caller, other callers may be waiting for it.
+	fetchCtx := context.WithoutCancel(c.logContext(ctx))
+
+	if valid {
+		if due {
+			c.fetches.DoChan(tokenKey, func() (interface{}, error) {
+				accessToken, err := c.fetchToken(fetchCtx)
+				if err != nil {
+					tflog.SubsystemWarn(fetchCtx, LogSubsystem, "background token refresh failed", map[string]interface{}{"err": err})
+					c.TokenMutex.Lock()
+					c.refreshFailedAt = time.Now()
+					c.TokenMutex.Unlock()
+				}
+				return accessToken, err
+			})
+		}
+		return cached, nil
 	}
 
-	tokenUrl := fmt.Sprintf("%s/protocol/openid-connect/token", strings.TrimRight(c.URL, "/"))
-	form := url.Values{}
-	form.Add("grant_type", "client_credentials")
-	form.Add("client_id", c.ApplicationUsername)
-	form.Add("client_secret", c.ApplicationPassword)
-	req, err := http.NewRequestWithContext(ctx, "POST", tokenUrl, strings.NewReader(form.Encode()))
-	tflog.Info(ctx, "creating request", map[string]interface{}{"url": tokenUrl, "err": err})
+	result, err, _ := c.fetches.Do(tokenKey, func() (interface{}, error) {
+		return c.fetchToken(fetchCtx)
+	})
+	if err != nil {
+		return "", err
+	}
+	return result.(string), nil
+}
+
+// isValidIn checks the cached token like [IsValidIn], but uses the expiration
+// time reported with the token, which also works for tokens which are no JWTs.
+// The caller must hold TokenMutex.
+func (c *AuthenticatorClient) isValidIn(delta time.Duration) bool {
+	if !c.tokenExpiresAt.IsZero() {
+		return time.Now().Add(delta).Before(c.tokenExpiresAt)
+	}
+	return IsValidIn(c.Token, delta)
+}
+
+// refreshIsDue reports whether RefreshFraction of the lifetime of the cached
+// token has passed and the last failed background refresh is at least
+// RefreshRetryInterval ago. The caller must hold TokenMutex.
+func (c *AuthenticatorClient) refreshIsDue() bool {
+	if c.tokenExpiresAt.IsZero() {
+		return false
+	}
+
+	retryInterval := c.RefreshRetryInterval
+	if retryInterval <= 0 {
+		retryInterval = DefaultRefreshRetryInterval
+	}
+	if time.Since(c.refreshFailedAt) < retryInterval {
+		return false
+	}
+
+	fraction := c.RefreshFraction
+	if fraction <= 0 || fraction > 1 {
+		fraction = DefaultRefreshFraction
+	}
+	lifetime := c.tokenExpiresAt.Sub(c.tokenIssuedAt)
+	return !time.Now().Before(c.tokenIssuedAt.Add(time.Duration(float64(lifetime) * fraction)))
+}
+
+// fetchToken gets a new token and caches it. The refresh token is used if the
+// token endpoint returned one, falling back to a new login if it is rejected.
+// Only one fetch runs at a time through the singleflight.
+func (c *AuthenticatorClient) fetchToken(ctx context.Context) (string, error) {
+	c.TokenMutex.Lock()
+	refreshToken := c.refreshToken
+	c.TokenMutex.Unlock()
+
+	issuedAt := time.Now()
 
+	var t *token
+	var err error
+	switch {
+	case len(c.CredentialCommand) > 0:
+		t, err = c.runCredentialCommand(ctx)
+	case refreshToken != "":
+		// the refreshed token has the scopes of the original grant (RFC 6749 section 6)
+		t, err = c.requestToken(ctx, url.Values{"grant_type": {"refresh_token"}, "refresh_token": {refreshToken}})
+		if err != nil {
+			tflog.SubsystemInfo(ctx, LogSubsystem, "refreshing token failed, logging in again", map[string]interface{}{"err": err})
+			t, err = c.requestToken(ctx, c.grant())
+		} else if t.refreshToken == "" {
+			// the refresh response may omit the refresh token, which then stays valid
+			t.refreshToken = refreshToken
+		}
+	default:
+		t, err = c.requestToken(ctx, c.grant())
+	}
 	if err != nil {
 		return "", err
 	}
 
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if len(c.CredentialCommand) == 0 {
+		if err := c.checkClaims(t.accessToken); err != nil {
+			return "", err
+		}
+	}
+
+	if t.expiresAt.IsZero() {
+		if expirationTime, err := ExpirationTime(t.accessToken); err == nil {
+			t.expiresAt = expirationTime
+		}
+	}
+
+	c.TokenMutex.Lock()
+	defer c.TokenMutex.Unlock()
+	c.Token = t.accessToken
+	c.refreshToken = t.refreshToken
+	c.tokenIssuedAt = issuedAt
+	c.tokenExpiresAt = t.expiresAt
+	return c.Token, nil
+}
+
+// grant returns the form parameters of the grant selected by the auth method.
+func (c *AuthenticatorClient) grant() url.Values {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if c.authMethod() == AuthMethodPassword {
+		form.Set("grant_type", "password")
+		form.Set("username", c.Username)
+		form.Set("password", c.Password)
+	}
+
+	if len(c.Scopes) > 0 {
+		form.Set("scope", strings.Join(c.Scopes, " "))
+	}
+	if c.Audience != "" {
+		form.Set("audience", c.Audience)
+	}
+	for key, value := range c.ExtraParams {
+		if !form.Has(key) {
+			form.Set(key, value)
+		}
+	}
+	return form
+}
+
+// checkClaims verifies that a JWT has been issued for the requested scopes
+// and audience and returns a [ClaimError] otherwise. Tokens which are no JWTs
+// cannot be checked.
+func (c *AuthenticatorClient) checkClaims(accessToken string) error {
+	if _, _, _, err := parts(accessToken); err != nil {
+		return nil
+	}
+
+	if len(c.Scopes) > 0 {
+		scopes, err := Scopes(accessToken)
+		if err != nil {
+			return fmt.Errorf("unable to read scopes of token: %w", err)
+		}
+		var missing []string
+		for _, scope := range c.Scopes {
+			if !slices.Contains(scopes, scope) {
+				missing = append(missing, scope)
+			}
+		}
+		if len(missing) > 0 {
+			return &ClaimError{Claim: "scope", Missing: missing, Granted: scopes}
+		}
+	}
+
+	if c.Audience != "" {
+		audiences, err := Audiences(accessToken)
+		if err != nil {
+			return fmt.Errorf("unable to read audience of token: %w", err)
+		}
+		if !slices.Contains(audiences, c.Audience) {
+			return &ClaimError{Claim: "aud", Missing: []string{c.Audience}, Granted: audiences}
+		}
+	}
+	return nil
+}
+
+// requestToken requests a token from the token endpoint with the grant parameters in form.
+func (c *AuthenticatorClient) requestToken(ctx context.Context, form url.Values) (*token, error) {
+	tokenUrl, err := c.tokenURL(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := c.tokenRequest(ctx, tokenUrl, form)
+	tflog.SubsystemInfo(ctx, LogSubsystem, "creating request", map[string]interface{}{"url": tokenUrl, "auth_method": c.authMethod(), "grant_type": form.Get("grant_type"), "err": err})
+
+	if err != nil {
+		return nil, err
+	}
 
 	resp, err := c.Client.Do(req)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		data, _ := io.ReadAll(resp.Body)
-		tflog.Info(ctx, "login failed", map[string]interface{}{
-			"status": resp.StatusCode,
-			"body":   data,
+		authError := newAuthError(resp.StatusCode, data)
+		tflog.SubsystemInfo(ctx, LogSubsystem, "login failed", map[string]interface{}{
+			"status":            resp.StatusCode,
+			"error":             authError.Code,
+			"error_description": authError.Description,
 		})
-		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, authError
 	}
 
-	var tokenResponse struct {
-		AccessToken string `json:"access_token"`
+	var response tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, err
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&tokenResponse); err != nil {
-		return "", err
+	t := &token{accessToken: response.AccessToken, refreshToken: response.RefreshToken}
+	if response.ExpiresIn > 0 {
+		t.expiresAt = time.Now().Add(time.Duration(response.ExpiresIn) * time.Second)
 	}
+	return t, nil
+}
 
-	c.Token = tokenResponse.AccessToken
-	return c.Token, nil
+func (c *AuthenticatorClient) authMethod() AuthMethod {
+	if c.AuthMethod == "" {
+		return AuthMethodClientSecretPost
+	}
+	return c.AuthMethod
+}
+
+// tokenRequest builds the request of the grant in form, authenticating the
+// client as selected by the auth method.
+func (c *AuthenticatorClient) tokenRequest(ctx context.Context, tokenUrl string, grant url.Values) (*http.Request, error) {
+	form := url.Values{}
+	for key, values := range grant {
+		form[key] = values
+	}
+	useBasicAuth := false
+
+	switch c.authMethod() {
+	case AuthMethodClientSecretPost:
+		form.Add("client_id", c.ApplicationUsername)
+		form.Add("client_secret", c.ApplicationPassword)
+	case AuthMethodClientSecretBasic:
+		useBasicAuth = true
+	case AuthMethodPrivateKeyJWT:
+		if c.PrivateKey == nil {
+			return nil, fmt.Errorf("auth method %s requires a private key", AuthMethodPrivateKeyJWT)
+		}
+		assertion, err := c.clientAssertion(tokenUrl)
+		if err != nil {
+			return nil, fmt.Errorf("unable to sign client assertion: %w", err)
+		}
+		form.Add("client_id", c.ApplicationUsername)
+		form.Add("client_assertion_type", clientAssertionType)
+		form.Add("client_assertion", assertion)
+	case AuthMethodPassword:
+		form.Add("client_id", c.ApplicationUsername)
+		if c.ApplicationPassword != "" {
+			form.Add("client_secret", c.ApplicationPassword)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported auth method %q", c.AuthMethod)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if useBasicAuth {
+		// RFC 6749 section 2.3.1 requires the credentials to be form encoded first
+		req.SetBasicAuth(url.QueryEscape(c.ApplicationUsername), url.QueryEscape(c.ApplicationPassword))
+	}
+	return req, nil
 }
@@ -0,0 +1,460 @@
+package authenticator_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Serviceware/terraform-provider-swp/internal/aipetest"
+	"github.com/Serviceware/terraform-provider-swp/internal/authenticator"
+)
+
+// newClient returns a client which logs in at the server with its client credentials.
+func newClient(server *aipetest.Server) *authenticator.AuthenticatorClient {
+	return &authenticator.AuthenticatorClient{
+		Client:              server.Client(),
+		ApplicationUsername: server.ClientID,
+		ApplicationPassword: server.ClientSecret,
+		URL:                 server.RealmURL(),
+	}
+}
+
+// pemEncode renders the key as PKCS #8 or, for RSA, as PKCS #1 PEM block.
+func pemEncode(t *testing.T, key crypto.Signer) []byte {
+	if rsaKey, ok := key.(*rsa.PrivateKey); ok {
+		return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func TestPrivateKeyJWT(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	for name, key := range map[string]crypto.Signer{"rsa": rsaKey, "ecdsa": ecKey, "ed25519": edKey} {
+		t.Run(name, func(t *testing.T) {
+			server := aipetest.NewServer(t)
+			server.ClientPublicKey = key.Public()
+
+			privateKey, err := authenticator.ParsePrivateKey(pemEncode(t, key))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			client := newClient(server)
+			client.AuthMethod = authenticator.AuthMethodPrivateKeyJWT
+			client.ApplicationPassword = ""
+			client.PrivateKey = privateKey
+
+			if _, err := client.Authenticate(context.Background()); err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		})
+	}
+}
+
+func TestPrivateKeyJWTWithWrongKey(t *testing.T) {
+	serverKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	clientKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	server := aipetest.NewServer(t)
+	server.ClientPublicKey = serverKey.Public()
+
+	client := newClient(server)
+	client.AuthMethod = authenticator.AuthMethodPrivateKeyJWT
+	client.PrivateKey = clientKey
+
+	if _, err := client.Authenticate(context.Background()); err == nil {
+		t.Errorf("expected login to fail")
+	}
+}
+
+func TestClientSecretBasic(t *testing.T) {
+	server := aipetest.NewServer(t)
+	server.ClientSecret = "s3cr:t&more"
+	client := newClient(server)
+	client.AuthMethod = authenticator.AuthMethodClientSecretBasic
+	client.ApplicationPassword = server.ClientSecret
+
+	if _, err := client.Authenticate(context.Background()); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func TestPasswordGrant(t *testing.T) {
+	server := aipetest.NewServer(t)
+	client := newClient(server)
+	client.AuthMethod = authenticator.AuthMethodPassword
+	client.ApplicationPassword = ""
+	client.Username = server.Username
+	client.Password = server.Password
+
+	if _, err := client.Authenticate(context.Background()); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	client.Token = ""
+	client.Password = "wrong"
+	server.RevokeRefreshTokens()
+	if _, err := client.Authenticate(context.Background()); err == nil {
+		t.Errorf("expected login to fail")
+	}
+}
+
+func TestTokenEndpointIsDiscoveredOnce(t *testing.T) {
+	server := aipetest.NewServer(t)
+	client := newClient(server)
+	ctx := context.Background()
+
+	for range 2 {
+		client.Token = ""
+		if _, err := client.Authenticate(ctx); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	if server.Discoveries() != 1 || server.TokenRequests() != 2 {
+		t.Errorf("expected 1 discovery and 2 token requests, got %d and %d", server.Discoveries(), server.TokenRequests())
+	}
+}
+
+func TestExplicitTokenURL(t *testing.T) {
+	server := aipetest.NewServer(t)
+	client := newClient(server)
+	client.URL = server.URL + "/realms/unknown"
+	client.TokenURL = server.TokenURL()
+
+	if _, err := client.Authenticate(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if server.Discoveries() != 0 {
+		t.Errorf("expected no discovery, got %d", server.Discoveries())
+	}
+}
+
+func TestFailedDiscovery(t *testing.T) {
+	server := aipetest.NewServer(t)
+	client := newClient(server)
+	client.URL = server.URL + "/realms/unknown"
+
+	if _, err := client.Authenticate(context.Background()); err == nil || !strings.Contains(err.Error(), "unable to discover token endpoint") {
+		t.Errorf("expected discovery error, got %v", err)
+	}
+}
+
+func TestAccessToken(t *testing.T) {
+	server := aipetest.NewServer(t)
+	token, err := newClient(server).Authenticate(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	client := &authenticator.AuthenticatorClient{AccessToken: token}
+	if accessToken, err := client.Authenticate(context.Background()); err != nil || accessToken != token {
+		t.Errorf("expected the access token, got %q and %v", accessToken, err)
+	}
+	if server.TokenRequests() != 1 {
+		t.Errorf("expected 1 token request, got %d", server.TokenRequests())
+	}
+}
+
+// TestCredentialCommandHelper is run as credential command by
+// TestCredentialCommand. It prints SWP_TEST_CREDENTIAL_OUTPUT and records the
+// invocation in SWP_TEST_CREDENTIAL_LOG.
+func TestCredentialCommandHelper(t *testing.T) {
+	output, ok := os.LookupEnv("SWP_TEST_CREDENTIAL_OUTPUT")
+	if !ok {
+		return
+	}
+
+	log, err := os.OpenFile(os.Getenv("SWP_TEST_CREDENTIAL_LOG"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err == nil {
+		fmt.Fprintln(log, "invoked")
+		log.Close()
+	}
+
+	fmt.Print(output)
+	os.Exit(0)
+}
+
+func TestCredentialCommand(t *testing.T) {
+	for name, tc := range map[string]struct {
+		tokenLifetime       time.Duration
+		expectedInvocations int
+	}{
+		"cached until expiry":      {tokenLifetime: 5 * time.Minute, expectedInvocations: 1},
+		"re-invoked when expiring": {tokenLifetime: 3 * time.Second, expectedInvocations: 2},
+	} {
+		t.Run(name, func(t *testing.T) {
+			server := aipetest.NewServer(t)
+			server.TokenLifetime = tc.tokenLifetime
+			token, err := newClient(server).Authenticate(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			output, _ := json.Marshal(authenticator.CredentialCommandOutput{AccessToken: token})
+			logFile := filepath.Join(t.TempDir(), "invocations")
+			t.Setenv("SWP_TEST_CREDENTIAL_OUTPUT", string(output))
+			t.Setenv("SWP_TEST_CREDENTIAL_LOG", logFile)
+
+			client := &authenticator.AuthenticatorClient{
+				CredentialCommand: []string{os.Args[0], "-test.run=^TestCredentialCommandHelper$"},
+			}
+			for range 2 {
+				if _, err := client.Authenticate(context.Background()); err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+			}
+
+			invocations, _ := os.ReadFile(logFile)
+			if count := strings.Count(string(invocations), "invoked"); count != tc.expectedInvocations {
+				t.Errorf("expected %d invocations, got %d", tc.expectedInvocations, count)
+			}
+		})
+	}
+}
+
+func TestFailingCredentialCommand(t *testing.T) {
+	t.Setenv("SWP_TEST_CREDENTIAL_OUTPUT", `{"token":"wrong key"}`)
+	client := &authenticator.AuthenticatorClient{
+		CredentialCommand: []string{os.Args[0], "-test.run=^TestCredentialCommandHelper$"},
+	}
+
+	if _, err := client.Authenticate(context.Background()); err == nil || !strings.Contains(err.Error(), "no access_token") {
+		t.Errorf("expected missing access_token error, got %v", err)
+	}
+}
+
+func TestRefreshTokenIsUsed(t *testing.T) {
+	server := aipetest.NewServer(t)
+	// shorter than the 5 seconds the cached token has to be valid, so every call fetches a token
+	server.TokenLifetime = 3 * time.Second
+	client := newClient(server)
+	ctx := context.Background()
+
+	for range 3 {
+		if _, err := client.Authenticate(ctx); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	if server.TokenRequests() != 3 || server.Refreshes() != 2 {
+		t.Errorf("expected 3 token requests with 2 refreshes, got %d and %d", server.TokenRequests(), server.Refreshes())
+	}
+
+	server.RevokeRefreshTokens()
+	if _, err := client.Authenticate(ctx); err != nil {
+		t.Fatalf("expected login after rejected refresh token, got %s", err)
+	}
+	if server.TokenRequests() != 4 || server.Refreshes() != 2 {
+		t.Errorf("expected 4 token requests with 2 refreshes, got %d and %d", server.TokenRequests(), server.Refreshes())
+	}
+}
+
+func TestRefreshTokenIsKeptIfNotReissued(t *testing.T) {
+	server := aipetest.NewServer(t)
+	server.TokenLifetime = 3 * time.Second
+	server.KeepRefreshTokens = true
+	client := newClient(server)
+	ctx := context.Background()
+
+	for range 4 {
+		if _, err := client.Authenticate(ctx); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	if server.TokenRequests() != 4 || server.Refreshes() != 3 {
+		t.Errorf("expected 4 token requests with 3 refreshes, got %d and %d", server.TokenRequests(), server.Refreshes())
+	}
+}
+
+func TestTokenIsRefreshedInBackground(t *testing.T) {
+	server := aipetest.NewServer(t)
+	server.TokenLifetime = 10 * time.Second
+	client := newClient(server)
+	client.RefreshFraction = 0.1
+	ctx := context.Background()
+
+	first, err := client.Authenticate(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	time.Sleep(time.Second)
+	cached, err := client.Authenticate(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if cached != first {
+		t.Errorf("expected the cached token while refreshing in the background")
+	}
+
+	for deadline := time.Now().Add(5 * time.Second); server.Refreshes() == 0 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if server.Refreshes() != 1 {
+		t.Fatalf("expected a background refresh, got %d", server.Refreshes())
+	}
+
+	refreshed, err := client.Authenticate(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if refreshed == first {
+		t.Errorf("expected the refreshed token")
+	}
+}
+
+// countingTransport counts the requests sent through it.
+type countingTransport struct {
+	http.RoundTripper
+	requests atomic.Int32
+}
+
+func (t *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	t.requests.Add(1)
+	return t.RoundTripper.RoundTrip(r)
+}
+
+func TestFailedBackgroundRefreshIsNotRepeatedImmediately(t *testing.T) {
+	server := aipetest.NewServer(t)
+	server.TokenLifetime = 20 * time.Second
+	client := newClient(server)
+	transport := &countingTransport{RoundTripper: server.Client().Transport}
+	client.Client = &http.Client{Transport: transport}
+	client.RefreshFraction = 0.05
+	client.RefreshRetryInterval = 2 * time.Second
+	ctx := context.Background()
+
+	first, err := client.Authenticate(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// both the refresh and the following login are rejected
+	client.ApplicationPassword = "rotated"
+	requests := transport.requests.Load()
+	time.Sleep(time.Second)
+
+	if _, err := client.Authenticate(ctx); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); transport.requests.Load() < requests+2 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	requests = transport.requests.Load()
+
+	for range 10 {
+		cached, err := client.Authenticate(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if cached != first {
+			t.Errorf("expected the cached token after a failed background refresh")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if transport.requests.Load() != requests {
+		t.Errorf("expected no token requests within the retry interval, got %d", transport.requests.Load()-requests)
+	}
+
+	time.Sleep(2 * time.Second)
+	if _, err := client.Authenticate(ctx); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); transport.requests.Load() == requests && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if transport.requests.Load() == requests {
+		t.Errorf("expected another background refresh after the retry interval")
+	}
+}
+
+func TestConcurrentCallersShareOneLogin(t *testing.T) {
+	server := aipetest.NewServer(t)
+	client := newClient(server)
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.Authenticate(context.Background()); err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if server.TokenRequests() != 1 {
+		t.Errorf("expected 1 token request, got %d", server.TokenRequests())
+	}
+}
+
+func TestScopesAudienceAndExtraParams(t *testing.T) {
+	server := aipetest.NewServer(t)
+	client := newClient(server)
+	client.Scopes = []string{"aipe:read", "aipe:write"}
+	client.Audience = "aipe"
+	client.ExtraParams = map[string]string{"resource": "https://aipe.example", "grant_type": "ignored"}
+
+	if _, err := client.Authenticate(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	form := server.LastTokenRequest()
+	if form.Get("scope") != "aipe:read aipe:write" || form.Get("audience") != "aipe" || form.Get("resource") != "https://aipe.example" || form.Get("grant_type") != "client_credentials" {
+		t.Errorf("unexpected token request %v", form)
+	}
+}
+
+func TestTokenClaimsAreChecked(t *testing.T) {
+	for name, tc := range map[string]struct {
+		scopes   []string
+		audience string
+		claim    string
+		expected string
+	}{
+		"scope not granted": {scopes: []string{"aipe:read", "aipe:admin"}, claim: "scope", expected: "requested scopes aipe:admin"},
+		"other audience":    {audience: "billing", claim: "aud", expected: "audience billing"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			server := aipetest.NewServer(t)
+			client := newClient(server)
+			client.Scopes = tc.scopes
+			client.Audience = tc.audience
+
+			_, err := client.Authenticate(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("expected error containing %q, got %v", tc.expected, err)
+			}
+			var claimError *authenticator.ClaimError
+			if !errors.As(err, &claimError) || claimError.Claim != tc.claim {
+				t.Errorf("expected ClaimError for claim %s, got %T", tc.claim, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package authenticator
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+const (
+	clientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+	// clientAssertionLifetime is kept short, the assertion is only used for one token request.
+	clientAssertionLifetime = time.Minute
+)
+
+// ParsePrivateKey parses the first PEM block of data as RSA, ECDSA or Ed25519
+// private key in PKCS #8, PKCS #1 or SEC 1 form.
+func ParsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM encoded private key found")
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type %T", key)
+		}
+		if _, _, err := signingAlgorithm(signer); err != nil {
+			return nil, err
+		}
+		return signer, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		if _, _, err := signingAlgorithm(key); err != nil {
+			return nil, err
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("unable to parse PEM block %q as private key", block.Type)
+}
+
+// signingAlgorithm returns the JWS algorithm and the hash used with the key.
+func signingAlgorithm(key crypto.Signer) (string, crypto.Hash, error) {
+	switch publicKey := key.Public().(type) {
+	case *rsa.PublicKey:
+		return "RS256", crypto.SHA256, nil
+	case *ecdsa.PublicKey:
+		switch publicKey.Curve.Params().BitSize {
+		case 256:
+			return "ES256", crypto.SHA256, nil
+		case 384:
+			return "ES384", crypto.SHA384, nil
+		case 521:
+			return "ES512", crypto.SHA512, nil
+		}
+		return "", 0, fmt.Errorf("unsupported elliptic curve %s", publicKey.Curve.Params().Name)
+	case ed25519.PublicKey:
+		return "EdDSA", 0, nil
+	}
+	return "", 0, fmt.Errorf("unsupported private key type %T", key)
+}
+
+// clientAssertion creates a JWT identifying the client at the token endpoint
+// as described in RFC 7523 section 2.2.
+func (c *AuthenticatorClient) clientAssertion(tokenUrl string) (string, error) {
+	algorithm, hash, err := signingAlgorithm(c.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+
+	header := map[string]string{"alg": algorithm, "typ": "JWT"}
+	if c.KeyID != "" {
+		header["kid"] = c.KeyID
+	}
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss": c.ApplicationUsername,
+		"sub": c.ApplicationUsername,
+		"aud": tokenUrl,
+		"jti": hex.EncodeToString(jti),
+		"iat": now.Unix(),
+		"exp": now.Add(clientAssertionLifetime).Unix(),
+	}
+
+	encodedHeader, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	encodedClaims, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(encodedHeader) + "." + base64.RawURLEncoding.EncodeToString(encodedClaims)
+	signature, err := sign(c.PrivateKey, hash, []byte(unsigned))
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func sign(key crypto.Signer, hash crypto.Hash, data []byte) ([]byte, error) {
+	digest := data
+	if hash != 0 {
+		h := hash.New()
+		h.Write(data)
+		digest = h.Sum(nil)
+	}
+
+	signature, err := key.Sign(rand.Reader, digest, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, ok := key.Public().(*ecdsa.PublicKey)
+	if !ok {
+		return signature, nil
+	}
+
+	// JWS uses the fixed size concatenation of r and s instead of ASN.1 (RFC 7518 section 3.4)
+	var parsed struct {
+		R, S *big.Int
+	}
+	if _, err := asn1.Unmarshal(signature, &parsed); err != nil {
+		return nil, err
+	}
+	size := (publicKey.Curve.Params().BitSize + 7) / 8
+	raw := make([]byte, 2*size)
+	parsed.R.FillBytes(raw[:size])
+	parsed.S.FillBytes(raw[size:])
+	return raw, nil
+}
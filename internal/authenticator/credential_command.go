@@ -0,0 +1,52 @@
+package authenticator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// CredentialCommandOutput is the JSON printed by a credential command to
+// stdout. ExpiresAt is optional for JWTs, whose exp claim is used instead.
+// Tokens which are no JWTs and have no ExpiresAt are not cached.
+type CredentialCommandOutput struct {
+	AccessToken string     `json:"access_token"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// runCredentialCommand runs CredentialCommand and returns the token it printed.
+func (c *AuthenticatorClient) runCredentialCommand(ctx context.Context) (*token, error) {
+	tflog.SubsystemInfo(ctx, LogSubsystem, "running credential command", map[string]interface{}{"command": c.CredentialCommand[0]})
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.CredentialCommand[0], c.CredentialCommand[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("credential command failed: %w: %s", err, message)
+		}
+		return nil, fmt.Errorf("credential command failed: %w", err)
+	}
+
+	var output CredentialCommandOutput
+	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+		return nil, fmt.Errorf("unable to parse output of credential command: %w", err)
+	}
+	if output.AccessToken == "" {
+		return nil, fmt.Errorf("output of credential command has no access_token")
+	}
+
+	t := &token{accessToken: output.AccessToken}
+	if output.ExpiresAt != nil {
+		t.expiresAt = *output.ExpiresAt
+	}
+	return t, nil
+}
@@ -0,0 +1,81 @@
+package authenticator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// openIDConfiguration is the part of the OpenID provider metadata used by the client.
+type openIDConfiguration struct {
+	Issuer        string `json:"issuer"`
+	TokenEndpoint string `json:"token_endpoint"`
+}
+
+// tokenURL returns TokenURL if set and otherwise the token endpoint announced
+// in the OpenID configuration of the issuer. The discovered endpoint is cached
+// for the lifetime of the client. It is only called while fetching a token,
+// which the singleflight runs once at a time.
+func (c *AuthenticatorClient) tokenURL(ctx context.Context) (string, error) {
+	if c.TokenURL != "" {
+		return c.TokenURL, nil
+	}
+	if c.tokenEndpoint != "" {
+		return c.tokenEndpoint, nil
+	}
+
+	configuration, err := c.discover(ctx)
+	if err != nil {
+		return "", fmt.Errorf("unable to discover token endpoint of %s, set the token URL explicitly: %w", c.URL, err)
+	}
+
+	c.tokenEndpoint = configuration.TokenEndpoint
+	return c.tokenEndpoint, nil
+}
+
+func (c *AuthenticatorClient) discover(ctx context.Context) (*openIDConfiguration, error) {
+	configurationUrl := fmt.Sprintf("%s/.well-known/openid-configuration", strings.TrimRight(c.URL, "/"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, configurationUrl, nil)
+	tflog.SubsystemInfo(ctx, LogSubsystem, "discovering OpenID configuration", map[string]interface{}{"url": configurationUrl, "err": err})
+
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		authError := newAuthError(resp.StatusCode, data)
+		tflog.SubsystemInfo(ctx, LogSubsystem, "discovery failed", map[string]interface{}{
+			"status":            resp.StatusCode,
+			"error":             authError.Code,
+			"error_description": authError.Description,
+		})
+		return nil, authError
+	}
+
+	var configuration openIDConfiguration
+	if err := json.NewDecoder(resp.Body).Decode(&configuration); err != nil {
+		return nil, err
+	}
+
+	if configuration.TokenEndpoint == "" {
+		return nil, fmt.Errorf("OpenID configuration has no token_endpoint")
+	}
+
+	tflog.SubsystemInfo(ctx, LogSubsystem, "discovered token endpoint", map[string]interface{}{"issuer": configuration.Issuer, "token_endpoint": configuration.TokenEndpoint})
+	return &configuration, nil
+}
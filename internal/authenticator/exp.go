@@ -33,6 +33,54 @@ func ExpirationTime(token string) (time.Time, error) {
 	return time.Unix(*parsedPayload.Exp, 0), nil
 }
 
+// Scopes is a simple helper function that extracts the space separated
+// scope claim from jwt. A token without scope claim has no scopes.
+func Scopes(token string) ([]string, error) {
+	parsedPayload := struct {
+		Scope string `json:"scope"`
+	}{}
+	if err := parsePayload(token, &parsedPayload); err != nil {
+		return nil, err
+	}
+	return strings.Fields(parsedPayload.Scope), nil
+}
+
+// Audiences is a simple helper function that extracts the audience claim
+// from jwt, which is either a single string or a list of strings.
+func Audiences(token string) ([]string, error) {
+	parsedPayload := struct {
+		Aud json.RawMessage `json:"aud"`
+	}{}
+	if err := parsePayload(token, &parsedPayload); err != nil {
+		return nil, err
+	} else if len(parsedPayload.Aud) == 0 {
+		return nil, nil
+	}
+
+	var audience string
+	if err := json.Unmarshal(parsedPayload.Aud, &audience); err == nil {
+		return []string{audience}, nil
+	}
+	var audiences []string
+	if err := json.Unmarshal(parsedPayload.Aud, &audiences); err != nil {
+		return nil, fmt.Errorf("token has invalid aud claim: %w", err)
+	}
+	return audiences, nil
+}
+
+func parsePayload(token string, v interface{}) error {
+	_, payloadPart, _, err := parts(token)
+	if err != nil {
+		return err
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(payloadPart)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(payload, v)
+}
+
 func parts(token string) (string, string, string, error) {
 	header, payloadAndSignature, hasHeader := strings.Cut(token, ".")
 	payload, signature, hasPayload := strings.Cut(payloadAndSignature, ".")
@@ -0,0 +1,47 @@
+package authenticator
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+const (
+	// LogSubsystem is the name of the tflog subsystem the authenticator logs to.
+	LogSubsystem = "authenticator"
+
+	// LogLevelEnvVar sets the log level of the subsystem independently of
+	// TF_LOG_PROVIDER.
+	LogLevelEnvVar = "TF_LOG_PROVIDER_SWP_AUTHENTICATOR"
+)
+
+// secretFields are the log fields and token request parameters whose values
+// are never logged.
+var secretFields = []string{"access_token", "refresh_token", "client_secret", "client_assertion", "password", "token"}
+
+// jwtPattern matches JSON Web Tokens, e.g. in error messages of the token endpoint.
+var jwtPattern = regexp.MustCompile(`eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+
+// logContext returns ctx with the logger of the authenticator subsystem. It
+// masks the secret fields, JWTs and the configured secrets wherever they
+// appear in a log entry.
+func (c *AuthenticatorClient) logContext(ctx context.Context) context.Context {
+	ctx = tflog.NewSubsystem(ctx, LogSubsystem, tflog.WithLevelFromEnv(LogLevelEnvVar), tflog.WithRootFields())
+	ctx = tflog.SubsystemMaskFieldValuesWithFieldKeys(ctx, LogSubsystem, secretFields...)
+	ctx = tflog.SubsystemMaskAllFieldValuesRegexes(ctx, LogSubsystem, jwtPattern)
+	ctx = tflog.SubsystemMaskMessageRegexes(ctx, LogSubsystem, jwtPattern)
+
+	var secrets []string
+	for _, secret := range []string{c.ApplicationPassword, c.Password, c.AccessToken} {
+		// an empty string would match everywhere
+		if secret != "" {
+			secrets = append(secrets, secret)
+		}
+	}
+	if len(secrets) > 0 {
+		ctx = tflog.SubsystemMaskAllFieldValuesStrings(ctx, LogSubsystem, secrets...)
+		ctx = tflog.SubsystemMaskMessageStrings(ctx, LogSubsystem, secrets...)
+	}
+	return ctx
+}
@@ -5,9 +5,11 @@ package provider
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 
 	"github.com/Serviceware/terraform-provider-swp/internal/aipe"
+	"github.com/hashicorp/terraform-plugin-framework-jsontypes/jsontypes"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/types"
@@ -26,8 +28,14 @@ type DataObjectDataSource struct {
 }
 
 type DataObjectDataSourceModel struct {
-	Id         types.String      `tfsdk:"id"`
-	Properties map[string]string `tfsdk:"properties"`
+	Id             types.String         `tfsdk:"id"`
+	Properties     map[string]string    `tfsdk:"properties"`
+	PropertiesJSON jsontypes.Normalized `tfsdk:"properties_json"`
+	DataObjectType types.String         `tfsdk:"type"`
+	CreatedAt      types.String         `tfsdk:"created_at"`
+	CreatedBy      types.String         `tfsdk:"created_by"`
+	ModifiedAt     types.String         `tfsdk:"modified_at"`
+	Version        types.String         `tfsdk:"version"`
 }
 
 func (d *DataObjectDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
@@ -46,7 +54,32 @@ func (d *DataObjectDataSource) Schema(ctx context.Context, req datasource.Schema
 			"properties": schema.MapAttribute{
 				ElementType:         types.StringType,
 				Computed:            true,
-				MarkdownDescription: "The properties in the AIPE, as stringd",
+				MarkdownDescription: "The properties in the AIPE, as strings. Values which are neither strings nor booleans are rendered as JSON",
+			},
+			"properties_json": schema.StringAttribute{
+				CustomType:          jsontypes.NormalizedType{},
+				Computed:            true,
+				MarkdownDescription: "The properties in the AIPE as JSON object, with their original types. Use `jsondecode` to access them",
+			},
+			"type": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "The data type name of the object",
+			},
+			"created_at": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "When the data object was created",
+			},
+			"created_by": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Who created the data object",
+			},
+			"modified_at": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "When the data object was modified the last time",
+			},
+			"version": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "The version of the data object, which changes with every modification",
 			},
 		},
 	}
@@ -87,12 +120,24 @@ func (d *DataObjectDataSource) Read(ctx context.Context, req datasource.ReadRequ
 			resp.State.RemoveResource(ctx)
 			return
 		}
-		resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to read example, got error: %s", err))
+		resp.Diagnostics.Append(clientErrorDiagnostics("Client Error", "Unable to read example", err, nil)...)
+		return
+	}
+	tflog.Info(ctx, "Successfully read data source", map[string]interface{}{"id": object.ID, "version": object.System.Version})
+
+	encoded, err := json.Marshal(object.Properties)
+	if err != nil {
+		resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to encode properties, got error: %s", err))
 		return
 	}
-	tflog.Info(ctx, "Successfully read data source", map[string]interface{}{"object": object, "error": err})
 
-	data.Properties = object
+	data.Properties = aipe.ConvertPropertiesToString(object.Properties)
+	data.PropertiesJSON = jsontypes.NewNormalizedValue(string(encoded))
+	data.DataObjectType = types.StringValue(object.System.TypeName)
+	data.CreatedAt = types.StringValue(object.System.CreatedAt)
+	data.CreatedBy = types.StringValue(object.System.CreatedBy)
+	data.ModifiedAt = types.StringValue(object.System.ModifiedAt)
+	data.Version = types.StringValue(object.System.Version)
 
 	tflog.Trace(ctx, "read a data source")
 
@@ -22,6 +22,12 @@ func TestAccAIPEDataObjectDataSource(t *testing.T) {
 				Config: testDataObjectDatasource(existingProperty, "bar"),
 				Check: resource.ComposeTestCheckFunc(
 					resource.TestCheckResourceAttr("data.swp_aipe_data_object.test_object", "properties."+existingProperty, "bar"),
+					resource.TestCheckResourceAttr("data.swp_aipe_data_object.test_object", "type", "test-object"),
+					resource.TestCheckResourceAttrSet("data.swp_aipe_data_object.test_object", "created_at"),
+					resource.TestCheckResourceAttrSet("data.swp_aipe_data_object.test_object", "created_by"),
+					resource.TestCheckResourceAttrSet("data.swp_aipe_data_object.test_object", "version"),
+					resource.TestCheckResourceAttrPair("data.swp_aipe_data_object.test_object", "modified_at", "swp_aipe_data_object.test_object", "modified_at"),
+					resource.TestCheckResourceAttrPair("data.swp_aipe_data_object.test_object", "version", "swp_aipe_data_object.test_object", "version"),
 				),
 			},
 		},
@@ -79,6 +85,27 @@ func TestAccAIPEDataObjectDeletedManually(t *testing.T) {
 	})
 }
 
+func TestAccAIPEDataObjectPropertiesJSON(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		PreCheck:                 func() { testAccPreCheck(t) },
+		Steps: []resource.TestStep{
+			// A literal "true" must stay a string when sent through properties_json
+			{
+				Config: testDataObjectPropertiesJSON(existingProperty, "true"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("swp_aipe_data_object.test_object", "properties_json", fmt.Sprintf(`{"%s":"true"}`, existingProperty)),
+					resource.TestCheckResourceAttr("data.swp_aipe_data_object.test_object", "properties."+existingProperty, "true"),
+				),
+			},
+			{
+				Config:      testDataObjectPropertiesJSONDuplicate(existingProperty),
+				ExpectError: regexp.MustCompile("Duplicate property"),
+			},
+		},
+	})
+}
+
 type DataObject struct {
 	ID string
 }
@@ -109,3 +136,32 @@ data "swp_aipe_data_object" "test_object" {
 }
 `, propertyName, propertyValue)
 }
+
+func testDataObjectPropertiesJSON(propertyName string, propertyValue string) string {
+	return fmt.Sprintf(`
+resource "swp_aipe_data_object" "test_object" {
+	type = "test-object"
+	properties_json = jsonencode({
+		%s = "%s"
+	})
+}
+
+data "swp_aipe_data_object" "test_object" {
+  id = swp_aipe_data_object.test_object.id
+}
+`, propertyName, propertyValue)
+}
+
+func testDataObjectPropertiesJSONDuplicate(propertyName string) string {
+	return fmt.Sprintf(`
+resource "swp_aipe_data_object" "test_object" {
+	type = "test-object"
+	properties = {
+		%s = "bar"
+	}
+	properties_json = jsonencode({
+		%s = "bar"
+	})
+}
+`, propertyName, propertyName)
+}
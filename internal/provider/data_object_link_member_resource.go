@@ -0,0 +1,262 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Serviceware/terraform-provider-swp/internal/aipe"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/identityschema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+var _ resource.Resource = &DataObjectLinkMemberResource{}
+var _ resource.ResourceWithImportState = &DataObjectLinkMemberResource{}
+var _ resource.ResourceWithIdentity = &DataObjectLinkMemberResource{}
+
+func NewDataObjectLinkMemberResource() resource.Resource {
+	return &DataObjectLinkMemberResource{}
+}
+
+// DataObjectLinkMemberResource manages a single link between a source and a
+// target object. Unlike [DataObjectLinkResource] it leaves all other links of
+// the relation alone.
+type DataObjectLinkMemberResource struct {
+	client *aipe.AIPEClient
+}
+
+type DataObjectLinkMemberResourceModel struct {
+	SourceID     types.String `tfsdk:"source_id"`
+	LinkName     types.String `tfsdk:"link_name"`
+	RelationName types.String `tfsdk:"relation_name"`
+	TargetID     types.String `tfsdk:"target_id"`
+}
+
+// DataObjectLinkMemberIdentityModel identifies a link member resource, which is one target of a relation.
+type DataObjectLinkMemberIdentityModel struct {
+	SourceID     types.String `tfsdk:"source_id"`
+	LinkName     types.String `tfsdk:"link_name"`
+	RelationName types.String `tfsdk:"relation_name"`
+	TargetID     types.String `tfsdk:"target_id"`
+}
+
+func (m DataObjectLinkMemberResourceModel) identity() DataObjectLinkMemberIdentityModel {
+	return DataObjectLinkMemberIdentityModel(m)
+}
+
+func (d *DataObjectLinkMemberResource) IdentitySchema(ctx context.Context, req resource.IdentitySchemaRequest, resp *resource.IdentitySchemaResponse) {
+	resp.IdentitySchema = identityschema.Schema{
+		Attributes: map[string]identityschema.Attribute{
+			"source_id": identityschema.StringAttribute{
+				RequiredForImport: true,
+				Description:       "The system.id of the source object",
+			},
+			"link_name": identityschema.StringAttribute{
+				RequiredForImport: true,
+				Description:       "The name of the link",
+			},
+			"relation_name": identityschema.StringAttribute{
+				RequiredForImport: true,
+				Description:       "The name of the relation on the source objects side",
+			},
+			"target_id": identityschema.StringAttribute{
+				RequiredForImport: true,
+				Description:       "The system.id of the target object",
+			},
+		},
+	}
+}
+
+func (d *DataObjectLinkMemberResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Links a data object to one target object. Other links of the relation are not touched, so several configurations can link targets to the same source object. Do not combine it with a `swp_aipe_data_object_link` of the same relation, which removes all targets it does not know about",
+		Attributes: map[string]schema.Attribute{
+			"source_id": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "The system.id of the source object",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"link_name": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "The name of the link. This is the name of the link ('incident-causes'), not the name of the relation (of which a link has 2 - 'caused-by' or 'causes').",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"relation_name": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "The name of the relation. This is the 'end' of the link on the source objects side",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"target_id": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "The system.id of the target object to link to",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+		},
+	}
+}
+
+func (r *DataObjectLinkMemberResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*aipe.AIPEClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *aipe.AIPEClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.client = client
+}
+
+func (d *DataObjectLinkMemberResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_aipe_data_object_link_member"
+}
+
+func (d *DataObjectLinkMemberResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data DataObjectLinkMemberResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	tflog.Info(ctx, "Creating link", map[string]interface{}{"source_id": data.SourceID.ValueString(), "target_id": data.TargetID.ValueString()})
+	err := d.client.UpdateDataObjectLinks(ctx, data.SourceID.ValueString(), data.LinkName.ValueString(), data.RelationName.ValueString(), []string{data.TargetID.ValueString()}, nil)
+	if err != nil {
+		resp.Diagnostics.Append(clientErrorDiagnostics("Failed to create link", "Unable to create link", err, nil)...)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+	if resp.Identity != nil {
+		resp.Diagnostics.Append(resp.Identity.Set(ctx, data.identity())...)
+	}
+}
+
+func (d *DataObjectLinkMemberResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data DataObjectLinkMemberResourceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	linkData, err := d.client.GetDataObjectLinks(ctx, data.SourceID.ValueString(), data.LinkName.ValueString(), data.RelationName.ValueString())
+	if aipe.ErrorIsNotFound(err) {
+		tflog.Info(ctx, "Source object of link not found, removing link from state", map[string]interface{}{"source_id": data.SourceID.ValueString()})
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.Append(clientErrorDiagnostics("Failed to get link data", "Unable to read link", err, nil)...)
+		return
+	}
+
+	if !slices.Contains(linkData, data.TargetID.ValueString()) {
+		tflog.Info(ctx, "Link not found, removing link from state", map[string]interface{}{"source_id": data.SourceID.ValueString(), "target_id": data.TargetID.ValueString()})
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+	if resp.Identity != nil {
+		resp.Diagnostics.Append(resp.Identity.Set(ctx, data.identity())...)
+	}
+}
+
+// Update is never called with changes, as all attributes require a replacement.
+func (d *DataObjectLinkMemberResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan DataObjectLinkMemberResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+	if resp.Identity != nil {
+		resp.Diagnostics.Append(resp.Identity.Set(ctx, plan.identity())...)
+	}
+}
+
+func (d *DataObjectLinkMemberResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data DataObjectLinkMemberResourceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	tflog.Info(ctx, "Deleting link", map[string]interface{}{"source_id": data.SourceID.ValueString(), "target_id": data.TargetID.ValueString()})
+	err := removeLinkTargets(ctx, d.client, data.SourceID.ValueString(), data.LinkName.ValueString(), data.RelationName.ValueString(), []string{data.TargetID.ValueString()})
+	if err != nil {
+		resp.Diagnostics.Append(clientErrorDiagnostics("Failed to delete link", "Unable to delete link", err, nil)...)
+	}
+}
+
+// ImportState accepts either an import ID in the form <source_id>/<link_name>/<relation_name>/<target_id>
+// or the resource identity. The object IDs are taken from both ends of the
+// import ID, so the relation name may contain slashes like in
+// swp_aipe_data_object_link. Read checks afterwards that the link exists.
+func (d *DataObjectLinkMemberResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	var identity DataObjectLinkMemberIdentityModel
+
+	if req.ID != "" {
+		var parts []string
+		if sourceID, rest, ok := strings.Cut(req.ID, "/"); ok {
+			if i := strings.LastIndex(rest, "/"); i >= 0 {
+				parts = append([]string{sourceID}, strings.SplitN(rest[:i], "/", 2)...)
+				parts = append(parts, rest[i+1:])
+			}
+		}
+		if len(parts) != 4 || slices.Contains(parts, "") {
+			resp.Diagnostics.AddError(
+				"Unexpected Import Identifier",
+				fmt.Sprintf("Expected import identifier with format: <source_id>/<link_name>/<relation_name>/<target_id>, where only the relation name may contain slashes. Got: %q", req.ID),
+			)
+			return
+		}
+		identity = DataObjectLinkMemberIdentityModel{
+			SourceID:     types.StringValue(parts[0]),
+			LinkName:     types.StringValue(parts[1]),
+			RelationName: types.StringValue(parts[2]),
+			TargetID:     types.StringValue(parts[3]),
+		}
+	} else {
+		resp.Diagnostics.Append(req.Identity.Get(ctx, &identity)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("source_id"), identity.SourceID)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("link_name"), identity.LinkName)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("relation_name"), identity.RelationName)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("target_id"), identity.TargetID)...)
+	if resp.Identity != nil {
+		resp.Diagnostics.Append(resp.Identity.Set(ctx, identity)...)
+	}
+}
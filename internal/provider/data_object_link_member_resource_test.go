@@ -0,0 +1,148 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+func TestAccAIPEDataObjectLinkMember(t *testing.T) {
+	var db01 = &DataObject{}
+	var db02 = &DataObject{}
+	var web01 = &DataObject{}
+	var cloudInc = &DataObject{}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		PreCheck:                 func() { testAccPreCheck(t) },
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataObjectLinkMembers(`["db01", "db02"]`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccDataObjectIDFetch("swp_aipe_data_object.server[\"db01\"]", db01),
+					testAccDataObjectIDFetch("swp_aipe_data_object.server[\"db02\"]", db02),
+					testAccDataObjectIDFetch("swp_aipe_data_object.server[\"web01\"]", web01),
+					testAccDataObjectIDFetch("swp_aipe_data_object.cloud_inc", cloudInc),
+					testAccDataObjectHasLinks(cloudInc, linkNameFromAIPE, relationNameFromAIPE, []*DataObject{db01, db02}),
+				),
+			},
+			{
+				// a link managed elsewhere must survive the removal of a member
+				PreConfig: func() {
+					if err := aipeClient.UpdateDataObjectLinks(context.Background(), cloudInc.ID, linkNameFromAIPE, relationNameFromAIPE, []string{web01.ID}, nil); err != nil {
+						t.Fatalf("unable to link web01: %s", err)
+					}
+				},
+				Config: testAccDataObjectLinkMembers(`["db01"]`),
+				Check:  testAccDataObjectHasLinks(cloudInc, linkNameFromAIPE, relationNameFromAIPE, []*DataObject{db01, web01}),
+			},
+			{
+				ResourceName:      "swp_aipe_data_object_link_member.hosted[\"db01\"]",
+				ImportState:       true,
+				ImportStateIdFunc: testAccDataObjectLinkMemberImportID("swp_aipe_data_object_link_member.hosted[\"db01\"]"),
+				ImportStateVerify: true,
+
+				ImportStateVerifyIdentifierAttribute: "target_id",
+			},
+			{
+				ResourceName:    "swp_aipe_data_object_link_member.hosted[\"db01\"]",
+				ImportState:     true,
+				ImportStateKind: resource.ImportBlockWithResourceIdentity,
+			},
+			{
+				ResourceName:  "swp_aipe_data_object_link_member.hosted[\"db01\"]",
+				ImportState:   true,
+				ImportStateId: "source/link/relation",
+				ExpectError:   regexp.MustCompile("Unexpected Import Identifier"),
+			},
+			{
+				// the member is created again if the link has been removed outside of Terraform
+				PreConfig: func() {
+					if err := aipeClient.UpdateDataObjectLinks(context.Background(), cloudInc.ID, linkNameFromAIPE, relationNameFromAIPE, nil, []string{db01.ID}); err != nil {
+						t.Fatalf("unable to unlink db01: %s", err)
+					}
+				},
+				Config: testAccDataObjectLinkMembers(`["db01"]`),
+				Check:  testAccDataObjectHasLinks(cloudInc, linkNameFromAIPE, relationNameFromAIPE, []*DataObject{db01, web01}),
+			},
+		},
+	})
+}
+
+var linkMemberImportIDTests = []struct {
+	id       string
+	expected map[string]string
+}{
+	{"42/hosting/hosts/4711", map[string]string{"source_id": "42", "link_name": "hosting", "relation_name": "hosts", "target_id": "4711"}},
+	{"42/hosting/hosts/of/4711", map[string]string{"source_id": "42", "link_name": "hosting", "relation_name": "hosts/of", "target_id": "4711"}},
+	{"42/hosting/4711", nil},
+	{"42/hosting/hosts/", nil},
+}
+
+func TestDataObjectLinkMemberImportID(t *testing.T) {
+	for _, tt := range linkMemberImportIDTests {
+		t.Run(tt.id, func(t *testing.T) {
+			attributes, diags := importState(t, &DataObjectLinkMemberResource{}, tt.id)
+			if tt.expected == nil {
+				if !diags.HasError() {
+					t.Errorf("expected error, got %v", attributes)
+				}
+				return
+			}
+			if diags.HasError() || !maps.Equal(attributes, tt.expected) {
+				t.Errorf("expected %v, got %v %v", tt.expected, attributes, diags)
+			}
+		})
+	}
+}
+
+func testAccDataObjectLinkMemberImportID(resourceName string) resource.ImportStateIdFunc {
+	return func(s *terraform.State) (string, error) {
+		resource, ok := s.RootModule().Resources[resourceName]
+		if !ok {
+			return "", fmt.Errorf("Not found: %s", resourceName)
+		}
+
+		attributes := resource.Primary.Attributes
+		return fmt.Sprintf("%s/%s/%s/%s", attributes["source_id"], attributes["link_name"], attributes["relation_name"], attributes["target_id"]), nil
+	}
+}
+
+func testAccDataObjectLinkMembers(members string) string {
+	return fmt.Sprintf(`
+resource "swp_aipe_data_object" "server" {
+	for_each = toset(["db01", "db02", "web01"])
+
+	type = "%s"
+	properties = {
+		"fqdn" = each.key
+	}
+}
+
+resource "swp_aipe_data_object" "cloud_inc" {
+	type = "%s"
+	properties = {
+		"support-portal" = "https://support.cloud-inc.example"
+	}
+}
+
+resource "swp_aipe_data_object_link_member" "hosted" {
+	for_each = toset(%s)
+
+	source_id     = swp_aipe_data_object.cloud_inc.id
+	link_name     = "%s"
+	relation_name = "%s"
+	target_id     = swp_aipe_data_object.server[each.key].id
+}
+`,
+		serverObjectTypeFromAIPE,
+		hosterObjectTypeFromAIPE,
+		members,
+		linkNameFromAIPE,
+		relationNameFromAIPE)
+}
@@ -3,17 +3,26 @@ package provider
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/Serviceware/terraform-provider-swp/internal/aipe"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/identityschema"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
 var _ resource.Resource = &DataObjectLinkResource{}
+var _ resource.ResourceWithImportState = &DataObjectLinkResource{}
+var _ resource.ResourceWithIdentity = &DataObjectLinkResource{}
 
 func NewDataObjectLinkResource() resource.Resource {
 	return &DataObjectLinkResource{}
@@ -33,6 +42,52 @@ type DataObjectLinkResourceModel struct {
 	RelationName types.String `tfsdk:"relation_name"`
 
 	TargetIDs []string `tfsdk:"target_ids"`
+
+	Mode types.String `tfsdk:"mode"`
+}
+
+const (
+	// linkModeAuthoritative manages all targets of the relation, links added
+	// outside of Terraform are removed.
+	linkModeAuthoritative = "authoritative"
+
+	// linkModeAdditive only manages the configured targets and leaves all
+	// other links of the relation untouched.
+	linkModeAdditive = "additive"
+)
+
+// DataObjectLinkIdentityModel identifies a link resource, which is one relation of a source object.
+type DataObjectLinkIdentityModel struct {
+	SourceID     types.String `tfsdk:"source_id"`
+	LinkName     types.String `tfsdk:"link_name"`
+	RelationName types.String `tfsdk:"relation_name"`
+}
+
+func (m DataObjectLinkResourceModel) identity() DataObjectLinkIdentityModel {
+	return DataObjectLinkIdentityModel{
+		SourceID:     m.SourceID,
+		LinkName:     m.LinkName,
+		RelationName: m.RelationName,
+	}
+}
+
+func (d *DataObjectLinkResource) IdentitySchema(ctx context.Context, req resource.IdentitySchemaRequest, resp *resource.IdentitySchemaResponse) {
+	resp.IdentitySchema = identityschema.Schema{
+		Attributes: map[string]identityschema.Attribute{
+			"source_id": identityschema.StringAttribute{
+				RequiredForImport: true,
+				Description:       "The system.id of the source object",
+			},
+			"link_name": identityschema.StringAttribute{
+				RequiredForImport: true,
+				Description:       "The name of the link",
+			},
+			"relation_name": identityschema.StringAttribute{
+				RequiredForImport: true,
+				Description:       "The name of the relation on the source objects side",
+			},
+		},
+	}
 }
 
 func (d *DataObjectLinkResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
@@ -65,6 +120,15 @@ func (d *DataObjectLinkResource) Schema(ctx context.Context, req resource.Schema
 				MarkdownDescription: "This is the list of target object IDs to link to",
 				Required:            true,
 			},
+			"mode": schema.StringAttribute{
+				MarkdownDescription: "How the targets of the relation are managed. With `authoritative` the relation links exactly the `target_ids`, links added outside of Terraform are removed. With `additive` only the links to the `target_ids` are managed and all other links are left untouched. Defaults to `authoritative`",
+				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString(linkModeAuthoritative),
+				Validators: []validator.String{
+					stringvalidator.OneOf(linkModeAuthoritative, linkModeAdditive),
+				},
+			},
 		},
 	}
 }
@@ -107,13 +171,16 @@ func (d *DataObjectLinkResource) Create(ctx context.Context, req resource.Create
 		tflog.Info(ctx, "Creating link", map[string]interface{}{"data": data.SourceID.ValueString()})
 		err := d.client.UpdateDataObjectLinks(ctx, data.SourceID.ValueString(), data.LinkName.ValueString(), data.RelationName.ValueString(), data.TargetIDs, nil)
 		if err != nil {
-			resp.Diagnostics.AddError("Failed to create link", err.Error())
+			resp.Diagnostics.Append(clientErrorDiagnostics("Failed to create link", "Unable to create link", err, nil)...)
 		}
 	} else {
 		tflog.Info(ctx, "No link to create", map[string]interface{}{"data": data.SourceID.ValueString()})
 	}
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+	if resp.Identity != nil {
+		resp.Diagnostics.Append(resp.Identity.Set(ctx, data.identity())...)
+	}
 }
 
 func (d *DataObjectLinkResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
@@ -126,18 +193,42 @@ func (d *DataObjectLinkResource) Read(ctx context.Context, req resource.ReadRequ
 	}
 
 	linkData, err := d.client.GetDataObjectLinks(ctx, data.SourceID.ValueString(), data.LinkName.ValueString(), data.RelationName.ValueString())
+	if aipe.ErrorIsNotFound(err) {
+		tflog.Info(ctx, "Source object of link not found, removing link from state", map[string]interface{}{"source_id": data.SourceID.ValueString()})
+		resp.State.RemoveResource(ctx)
+		return
+	}
 	if err != nil {
-		resp.Diagnostics.AddError("Failed to get link data", err.Error())
+		resp.Diagnostics.Append(clientErrorDiagnostics("Failed to get link data", "Unable to read link", err, nil)...)
 		return
 	}
 
-	if linkData == nil {
+	switch {
+	case data.Mode.ValueString() == linkModeAdditive:
+		// Only the managed targets are read, so links added outside of
+		// Terraform do not show up as drift.
+		targetIDs := []string{}
+		for _, id := range data.TargetIDs {
+			if slices.Contains(linkData, id) {
+				targetIDs = append(targetIDs, id)
+			}
+		}
+		data.TargetIDs = targetIDs
+	case linkData == nil:
 		data.TargetIDs = []string{}
-	} else {
+	default:
 		data.TargetIDs = linkData
 	}
 
+	// Imported resources have no value yet
+	if data.Mode.IsNull() {
+		data.Mode = types.StringValue(linkModeAuthoritative)
+	}
+
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+	if resp.Identity != nil {
+		resp.Diagnostics.Append(resp.Identity.Set(ctx, data.identity())...)
+	}
 }
 
 func (d *DataObjectLinkResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
@@ -154,20 +245,43 @@ func (d *DataObjectLinkResource) Update(ctx context.Context, req resource.Update
 	var stateTargetIDs = state.TargetIDs
 	var planTargetIDs = plan.TargetIDs
 
+	if plan.Mode.ValueString() == linkModeAuthoritative && state.Mode.ValueString() == linkModeAdditive {
+		// Taking over the relation removes the links added outside of Terraform,
+		// which are not in the state of the additive mode.
+		linkData, err := d.client.GetDataObjectLinks(ctx, state.SourceID.ValueString(), state.LinkName.ValueString(), state.RelationName.ValueString())
+		if err != nil {
+			resp.Diagnostics.Append(clientErrorDiagnostics("Failed to get link data", "Unable to read link", err, nil)...)
+			return
+		}
+		stateTargetIDs = linkData
+	}
+
 	add, remove := diffStateAndPlanIDs(stateTargetIDs, planTargetIDs)
 
+	if plan.Mode.ValueString() == linkModeAdditive && state.Mode.ValueString() != linkModeAdditive {
+		// The state of the authoritative mode contains all targets of the
+		// relation, and it cannot be told which of them Terraform configured.
+		// Nothing is removed on the switch, so links added outside of
+		// Terraform survive it.
+		remove = nil
+	}
+
 	if len(add) == 0 && len(remove) == 0 {
 		tflog.Info(ctx, "No changes to link", map[string]interface{}{"data": plan.SourceID.ValueString()})
 	} else {
 		tflog.Info(ctx, "Updating link", map[string]interface{}{"add": add, "remove": remove})
 		err := d.client.UpdateDataObjectLinks(ctx, plan.SourceID.ValueString(), plan.LinkName.ValueString(), plan.RelationName.ValueString(), add, remove)
 		if err != nil {
-			resp.Diagnostics.AddError("Failed to update link", err.Error())
+			resp.Diagnostics.Append(clientErrorDiagnostics("Failed to update link", "Unable to update link", err, nil)...)
 		}
 	}
 	state.TargetIDs = plan.TargetIDs
+	state.Mode = plan.Mode
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+	if resp.Identity != nil {
+		resp.Diagnostics.Append(resp.Identity.Set(ctx, state.identity())...)
+	}
 }
 
 func diffStateAndPlanIDs(stateTargetIDs []string, planTargetIDs []string) ([]string, []string) {
@@ -209,11 +323,99 @@ func (d *DataObjectLinkResource) Delete(ctx context.Context, req resource.Delete
 
 	if len(data.TargetIDs) > 0 {
 		tflog.Info(ctx, "Deleting link", map[string]interface{}{"data": data.SourceID.ValueString()})
-		err := d.client.UpdateDataObjectLinks(ctx, data.SourceID.ValueString(), data.LinkName.ValueString(), data.RelationName.ValueString(), nil, data.TargetIDs)
+		err := removeLinkTargets(ctx, d.client, data.SourceID.ValueString(), data.LinkName.ValueString(), data.RelationName.ValueString(), data.TargetIDs)
 		if err != nil {
-			resp.Diagnostics.AddError("Failed to delete link", err.Error())
+			resp.Diagnostics.Append(clientErrorDiagnostics("Failed to delete link", "Unable to delete link", err, nil)...)
 		}
 	} else {
 		tflog.Info(ctx, "No link to delete", map[string]interface{}{"data": data.SourceID.ValueString()})
 	}
 }
+
+// removeLinkTargets removes the links from the source object to the targets,
+// see [removeLinks].
+func removeLinkTargets(ctx context.Context, client *aipe.AIPEClient, sourceID string, linkName string, relationName string, targetIDs []string) error {
+	return removeLinks(ctx, client, sourceID, []aipe.LinkDefinition{{LinkName: linkName, RelationName: relationName, Remove: targetIDs}})
+}
+
+// removeLinks removes the links from the source object to the targets in
+// Remove of each link definition. Links which are already gone are no error:
+// if the source object has been deleted, there is nothing left to remove. The
+// AIPE rejects the whole request if one of the targets has been deleted, so in
+// case of an error only the targets which are still linked are removed.
+func removeLinks(ctx context.Context, client *aipe.AIPEClient, sourceID string, links []aipe.LinkDefinition) error {
+	err := client.UpdateDataObjectLinksBatch(ctx, sourceID, links)
+	if err == nil || aipe.ErrorIsNotFound(err) {
+		return nil
+	}
+
+	var remaining []aipe.LinkDefinition
+	missing := false
+	for _, link := range links {
+		linked, readErr := client.GetDataObjectLinks(ctx, sourceID, link.LinkName, link.RelationName)
+		if aipe.ErrorIsNotFound(readErr) {
+			return nil
+		}
+		if readErr != nil {
+			return err
+		}
+
+		var targetIDs []string
+		for _, id := range link.Remove {
+			if slices.Contains(linked, id) {
+				targetIDs = append(targetIDs, id)
+			}
+		}
+		if len(targetIDs) < len(link.Remove) {
+			missing = true
+		}
+		if len(targetIDs) > 0 {
+			remaining = append(remaining, aipe.LinkDefinition{LinkName: link.LinkName, RelationName: link.RelationName, Remove: targetIDs})
+		}
+	}
+	if len(remaining) == 0 {
+		return nil
+	}
+	if !missing {
+		// all targets still exist, so the error has another cause
+		return err
+	}
+
+	tflog.Info(ctx, "Removing remaining links", map[string]interface{}{"source_id": sourceID, "links": len(remaining)})
+	return client.UpdateDataObjectLinksBatch(ctx, sourceID, remaining)
+}
+
+// ImportState accepts either an import ID in the form <source_id>/<link_name>/<relation_name>
+// or the resource identity. The relation name is the rest of the import ID, so
+// it may contain slashes. The target_ids are read from the AIPE afterwards.
+func (d *DataObjectLinkResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	var identity DataObjectLinkIdentityModel
+
+	if req.ID != "" {
+		parts := strings.SplitN(req.ID, "/", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			resp.Diagnostics.AddError(
+				"Unexpected Import Identifier",
+				fmt.Sprintf("Expected import identifier with format: <source_id>/<link_name>/<relation_name>, where only the relation name may contain slashes. Got: %q", req.ID),
+			)
+			return
+		}
+		identity = DataObjectLinkIdentityModel{
+			SourceID:     types.StringValue(parts[0]),
+			LinkName:     types.StringValue(parts[1]),
+			RelationName: types.StringValue(parts[2]),
+		}
+	} else {
+		resp.Diagnostics.Append(req.Identity.Get(ctx, &identity)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("source_id"), identity.SourceID)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("link_name"), identity.LinkName)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("relation_name"), identity.RelationName)...)
+	if resp.Identity != nil {
+		resp.Diagnostics.Append(resp.Identity.Set(ctx, identity)...)
+	}
+}
@@ -3,8 +3,20 @@ package provider
 import (
 	"context"
 	"fmt"
+	"maps"
+	"regexp"
+	"slices"
 	"testing"
 
+	"github.com/Serviceware/terraform-provider-swp/internal/aipe"
+	"github.com/Serviceware/terraform-provider-swp/internal/aipetest"
+	"github.com/Serviceware/terraform-provider-swp/internal/authenticator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
 	"github.com/hashicorp/terraform-plugin-testing/terraform"
 )
@@ -120,6 +132,319 @@ func TestAccAIPEDataObjectLinkHappyCase(t *testing.T) {
 	})
 }
 
+func TestAccAIPEDataObjectLinkSourceDeleted(t *testing.T) {
+	var cloudInc = &DataObject{}
+	var db01 = &DataObject{}
+	var db02 = &DataObject{}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		PreCheck:                 func() { testAccPreCheck(t) },
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataObjectLinkSimple(),
+				Check:  testAccDataObjectIDFetch("swp_aipe_data_object.cloud_inc", cloudInc),
+			},
+			{
+				PreConfig: func() {
+					if err := aipeClient.DeleteObject(context.Background(), cloudInc.ID); err != nil {
+						t.Fatalf("unable to delete source object: %s", err)
+					}
+				},
+				Config: testAccDataObjectLinkSimple(),
+				Check: resource.ComposeTestCheckFunc(
+					testAccDataObjectIDFetch("swp_aipe_data_object.db01", db01),
+					testAccDataObjectIDFetch("swp_aipe_data_object.db02", db02),
+					testAccDataObjectIDFetch("swp_aipe_data_object.cloud_inc", cloudInc),
+					testAccDataObjectHasLinks(cloudInc, linkNameFromAIPE, relationNameFromAIPE, []*DataObject{db01, db02}),
+				),
+			},
+		},
+	})
+}
+
+func TestAccAIPEDataObjectLinkAdditive(t *testing.T) {
+	var db01 = &DataObject{}
+	var db02 = &DataObject{}
+	var cloudInc = &DataObject{}
+	var foreign string
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		PreCheck:                 func() { testAccPreCheck(t) },
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataObjectLinkMode("additive", "db01"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccDataObjectIDFetch("swp_aipe_data_object.db01", db01),
+					testAccDataObjectIDFetch("swp_aipe_data_object.db02", db02),
+					testAccDataObjectIDFetch("swp_aipe_data_object.cloud_inc", cloudInc),
+					resource.TestCheckResourceAttr("swp_aipe_data_object_link.hosted", "mode", "additive"),
+				),
+			},
+			{
+				// a link added by automation shows no drift
+				PreConfig: func() {
+					id, err := aipeClient.CreateObject(context.Background(), serverObjectTypeFromAIPE, map[string]interface{}{"fqdn": "foreign"})
+					if err != nil {
+						t.Fatalf("unable to create foreign server: %s", err)
+					}
+					foreign = id
+					if err := aipeClient.UpdateDataObjectLinks(context.Background(), cloudInc.ID, linkNameFromAIPE, relationNameFromAIPE, []string{foreign}, nil); err != nil {
+						t.Fatalf("unable to link foreign server: %s", err)
+					}
+				},
+				Config:   testAccDataObjectLinkMode("additive", "db01"),
+				PlanOnly: true,
+			},
+			{
+				Config: testAccDataObjectLinkMode("additive", "db02"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("swp_aipe_data_object_link.hosted", "target_ids.#", "1"),
+					testAccDataObjectHasLinks(cloudInc, linkNameFromAIPE, relationNameFromAIPE, []*DataObject{db02, {ID: foreign}}),
+				),
+			},
+			{
+				// switching to authoritative mode takes over and removes the foreign link
+				Config: testAccDataObjectLinkMode("authoritative", "db02"),
+				Check:  testAccDataObjectHasLinks(cloudInc, linkNameFromAIPE, relationNameFromAIPE, []*DataObject{db02}),
+			},
+		},
+	})
+}
+
+func TestAccAIPEDataObjectLinkSwitchToAdditive(t *testing.T) {
+	var db01 = &DataObject{}
+	var cloudInc = &DataObject{}
+	var foreign string
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		PreCheck:                 func() { testAccPreCheck(t) },
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataObjectLinkMode("authoritative", "db01"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccDataObjectIDFetch("swp_aipe_data_object.db01", db01),
+					testAccDataObjectIDFetch("swp_aipe_data_object.cloud_inc", cloudInc),
+				),
+			},
+			{
+				// a link added by automation survives the switch to additive mode
+				PreConfig: func() {
+					id, err := aipeClient.CreateObject(context.Background(), serverObjectTypeFromAIPE, map[string]interface{}{"fqdn": "foreign"})
+					if err != nil {
+						t.Fatalf("unable to create foreign server: %s", err)
+					}
+					foreign = id
+					if err := aipeClient.UpdateDataObjectLinks(context.Background(), cloudInc.ID, linkNameFromAIPE, relationNameFromAIPE, []string{foreign}, nil); err != nil {
+						t.Fatalf("unable to link foreign server: %s", err)
+					}
+				},
+				Config: testAccDataObjectLinkMode("additive", "db01"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("swp_aipe_data_object_link.hosted", "target_ids.#", "1"),
+					testAccDataObjectHasLinks(cloudInc, linkNameFromAIPE, relationNameFromAIPE, []*DataObject{db01, {ID: foreign}}),
+				),
+			},
+		},
+	})
+}
+
+func testAccDataObjectLinkMode(mode string, target string) string {
+	return fmt.Sprintf(`
+resource "swp_aipe_data_object" "db01" {
+	type = "%[1]s"
+	properties = {
+		"fqdn" = "db01"
+	}
+}
+
+resource "swp_aipe_data_object" "db02" {
+	type = "%[1]s"
+	properties = {
+		"fqdn" = "db02"
+	}
+}
+
+resource "swp_aipe_data_object" "cloud_inc" {
+	type = "%[2]s"
+	properties = {
+		"support-portal" = "https://support.cloud-inc.example"
+	}
+}
+
+resource "swp_aipe_data_object_link" "hosted" {
+	source_id     = swp_aipe_data_object.cloud_inc.id
+	link_name     = "%[3]s"
+	relation_name = "%[4]s"
+	mode          = "%[5]s"
+
+	target_ids = [swp_aipe_data_object.%[6]s.id]
+}
+`,
+		serverObjectTypeFromAIPE,
+		hosterObjectTypeFromAIPE,
+		linkNameFromAIPE,
+		relationNameFromAIPE,
+		mode,
+		target)
+}
+
+func TestRemoveLinkTargets(t *testing.T) {
+	server := aipetest.NewServer(t)
+	client := &aipe.AIPEClient{
+		HTTPClient: server.Client(),
+		URL:        server.URL,
+		Authenticator: &authenticator.AuthenticatorClient{
+			Client:              server.Client(),
+			ApplicationUsername: server.ClientID,
+			ApplicationPassword: server.ClientSecret,
+			URL:                 server.RealmURL(),
+		},
+	}
+	ctx := context.Background()
+
+	hoster, _ := client.CreateObject(ctx, hosterObjectTypeFromAIPE, map[string]interface{}{"name": "Cloud Inc."})
+	var servers []string
+	for _, fqdn := range []string{"db01", "db02", "db03"} {
+		id, err := client.CreateObject(ctx, serverObjectTypeFromAIPE, map[string]interface{}{"fqdn": fqdn})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		servers = append(servers, id)
+	}
+	if err := client.UpdateDataObjectLinks(ctx, hoster, linkNameFromAIPE, relationNameFromAIPE, servers, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// a deleted target must not prevent the removal of the other links
+	if err := client.DeleteObject(ctx, servers[0]); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := removeLinkTargets(ctx, client, hoster, linkNameFromAIPE, relationNameFromAIPE, servers[:2]); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if links := server.Links(hoster, linkNameFromAIPE, relationNameFromAIPE); !slices.Equal(links, servers[2:]) {
+		t.Errorf("expected %v, got %v", servers[2:], links)
+	}
+
+	if err := client.DeleteObject(ctx, hoster); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := removeLinkTargets(ctx, client, hoster, linkNameFromAIPE, relationNameFromAIPE, servers[2:]); err != nil {
+		t.Errorf("expected missing source to be ignored, got %s", err)
+	}
+
+	if err := removeLinkTargets(ctx, client, servers[1], "unknown-link", relationNameFromAIPE, servers[2:]); err == nil {
+		t.Errorf("expected error for unknown link")
+	}
+}
+
+// importState imports the resource by import ID and returns the string
+// attributes of the imported state.
+func importState(t *testing.T, r fwresource.ResourceWithImportState, id string) (map[string]string, diag.Diagnostics) {
+	ctx := context.Background()
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+
+	resp := &fwresource.ImportStateResponse{
+		State: tfsdk.State{Schema: schemaResp.Schema, Raw: tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil)},
+	}
+	r.ImportState(ctx, fwresource.ImportStateRequest{ID: id}, resp)
+	if resp.Diagnostics.HasError() {
+		return nil, resp.Diagnostics
+	}
+
+	attributes := map[string]string{}
+	for _, name := range []string{"source_id", "link_name", "relation_name", "target_id"} {
+		if _, ok := schemaResp.Schema.Attributes[name]; !ok {
+			continue
+		}
+		var value types.String
+		resp.Diagnostics.Append(resp.State.GetAttribute(ctx, path.Root(name), &value)...)
+		attributes[name] = value.ValueString()
+	}
+	return attributes, resp.Diagnostics
+}
+
+var linkImportIDTests = []struct {
+	id       string
+	expected map[string]string
+}{
+	{"42/hosting/hosted-by", map[string]string{"source_id": "42", "link_name": "hosting", "relation_name": "hosted-by"}},
+	{"42/hosting/hosted/by", map[string]string{"source_id": "42", "link_name": "hosting", "relation_name": "hosted/by"}},
+	{"42/hosting", nil},
+	{"42//hosted-by", nil},
+}
+
+func TestDataObjectLinkImportID(t *testing.T) {
+	for _, tt := range linkImportIDTests {
+		t.Run(tt.id, func(t *testing.T) {
+			attributes, diags := importState(t, &DataObjectLinkResource{}, tt.id)
+			if tt.expected == nil {
+				if !diags.HasError() {
+					t.Errorf("expected error, got %v", attributes)
+				}
+				return
+			}
+			if diags.HasError() || !maps.Equal(attributes, tt.expected) {
+				t.Errorf("expected %v, got %v %v", tt.expected, attributes, diags)
+			}
+		})
+	}
+}
+
+func TestAccAIPEDataObjectLinkImport(t *testing.T) {
+	resourceName := "swp_aipe_data_object_link.cloud-inc-hosting-both-dbs"
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		PreCheck:                 func() { testAccPreCheck(t) },
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataObjectLinkSimple(),
+			},
+			{
+				ResourceName:                         resourceName,
+				ImportState:                          true,
+				ImportStateIdFunc:                    testAccDataObjectLinkImportID(resourceName),
+				ImportStateVerify:                    true,
+				ImportStateVerifyIdentifierAttribute: "source_id",
+			},
+			{
+				ResourceName:      resourceName,
+				ImportState:       true,
+				ImportStateKind:   resource.ImportBlockWithID,
+				ImportStateIdFunc: testAccDataObjectLinkImportID(resourceName),
+			},
+			{
+				ResourceName:    resourceName,
+				ImportState:     true,
+				ImportStateKind: resource.ImportBlockWithResourceIdentity,
+			},
+			{
+				ResourceName:  resourceName,
+				ImportState:   true,
+				ImportStateId: "only-a-source-id",
+				ExpectError:   regexp.MustCompile("Unexpected Import Identifier"),
+			},
+		},
+	})
+}
+
+func testAccDataObjectLinkImportID(resourceName string) resource.ImportStateIdFunc {
+	return func(s *terraform.State) (string, error) {
+		resource, ok := s.RootModule().Resources[resourceName]
+		if !ok {
+			return "", fmt.Errorf("Not found: %s", resourceName)
+		}
+
+		attributes := resource.Primary.Attributes
+		return fmt.Sprintf("%s/%s/%s", attributes["source_id"], attributes["link_name"], attributes["relation_name"]), nil
+	}
+}
+
 var serverObjectTypeFromAIPE = "server"
 var hosterObjectTypeFromAIPE = "hoster"
 var linkNameFromAIPE = "server-hosted-by-hoster"
@@ -0,0 +1,300 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/Serviceware/terraform-provider-swp/internal/aipe"
+	"github.com/hashicorp/terraform-plugin-framework-validators/setvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+var _ resource.Resource = &DataObjectLinksResource{}
+var _ resource.ResourceWithValidateConfig = &DataObjectLinksResource{}
+
+func NewDataObjectLinksResource() resource.Resource {
+	return &DataObjectLinksResource{}
+}
+
+// DataObjectLinksResource manages the targets of several relations of one
+// source object. All changes are sent to the AIPE in a single request.
+type DataObjectLinksResource struct {
+	client *aipe.AIPEClient
+}
+
+type DataObjectLinksResourceModel struct {
+	SourceID types.String               `tfsdk:"source_id"`
+	Links    []DataObjectLinksLinkModel `tfsdk:"link"`
+}
+
+// DataObjectLinksLinkModel is one link block, which manages the targets of one relation.
+type DataObjectLinksLinkModel struct {
+	LinkName     types.String `tfsdk:"link_name"`
+	RelationName types.String `tfsdk:"relation_name"`
+	TargetIDs    []string     `tfsdk:"target_ids"`
+}
+
+// key identifies the relation of the link block.
+func (m DataObjectLinksLinkModel) key() string {
+	return m.LinkName.ValueString() + "/" + m.RelationName.ValueString()
+}
+
+// linkDefinitions returns the changes which turn the links of the state into
+// the links of the plan, at most one link definition per relation. Relations
+// removed from the plan lose all their targets.
+func linkDefinitions(state []DataObjectLinksLinkModel, plan []DataObjectLinksLinkModel) []aipe.LinkDefinition {
+	stateTargetIDs := make(map[string][]string)
+	for _, link := range state {
+		stateTargetIDs[link.key()] = link.TargetIDs
+	}
+
+	var links []aipe.LinkDefinition
+	for _, link := range plan {
+		add, remove := diffStateAndPlanIDs(stateTargetIDs[link.key()], link.TargetIDs)
+		delete(stateTargetIDs, link.key())
+		if len(add) > 0 || len(remove) > 0 {
+			links = append(links, aipe.LinkDefinition{LinkName: link.LinkName.ValueString(), RelationName: link.RelationName.ValueString(), Add: add, Remove: remove})
+		}
+	}
+	for _, link := range state {
+		if targetIDs, ok := stateTargetIDs[link.key()]; ok && len(targetIDs) > 0 {
+			links = append(links, aipe.LinkDefinition{LinkName: link.LinkName.ValueString(), RelationName: link.RelationName.ValueString(), Remove: targetIDs})
+		}
+	}
+	return links
+}
+
+// linkBlock is the schema of the link blocks, which manage the targets of one
+// relation each.
+func linkBlock() schema.SetNestedBlock {
+	return schema.SetNestedBlock{
+		MarkdownDescription: "The targets of one relation of the source object. Each relation may only appear once",
+		NestedObject: schema.NestedBlockObject{
+			Attributes: map[string]schema.Attribute{
+				"link_name": schema.StringAttribute{
+					Required:            true,
+					MarkdownDescription: "The name of the link. This is the name of the link ('incident-causes'), not the name of the relation (of which a link has 2 - 'caused-by' or 'causes').",
+				},
+				"relation_name": schema.StringAttribute{
+					Required:            true,
+					MarkdownDescription: "The name of the relation. This is the 'end' of the link on the source objects side",
+				},
+				"target_ids": schema.SetAttribute{
+					ElementType:         types.StringType,
+					MarkdownDescription: "This is the list of target object IDs to link to",
+					Required:            true,
+				},
+			},
+		},
+	}
+}
+
+// validateLinkBlocks reports relations which are configured in more than one
+// link block of the config.
+func validateLinkBlocks(ctx context.Context, config tfsdk.Config) diag.Diagnostics {
+	var diags diag.Diagnostics
+	var links types.Set
+
+	diags.Append(config.GetAttribute(ctx, path.Root("link"), &links)...)
+	if diags.HasError() || links.IsNull() || links.IsUnknown() {
+		return diags
+	}
+
+	var blocks []struct {
+		LinkName     types.String `tfsdk:"link_name"`
+		RelationName types.String `tfsdk:"relation_name"`
+		TargetIDs    types.Set    `tfsdk:"target_ids"`
+	}
+	diags.Append(links.ElementsAs(ctx, &blocks, false)...)
+	if diags.HasError() {
+		return diags
+	}
+
+	var keys []string
+	for _, block := range blocks {
+		if block.LinkName.IsUnknown() || block.RelationName.IsUnknown() {
+			continue
+		}
+		key := DataObjectLinksLinkModel{LinkName: block.LinkName, RelationName: block.RelationName}.key()
+		if slices.Contains(keys, key) {
+			diags.AddAttributeError(
+				path.Root("link"),
+				"Duplicate relation",
+				fmt.Sprintf("The relation %s of link %s is configured in more than one link block", block.RelationName.ValueString(), block.LinkName.ValueString()),
+			)
+		}
+		keys = append(keys, key)
+	}
+	return diags
+}
+
+// readLinkBlocks reads the current targets of the relations of the link
+// blocks from the AIPE.
+func readLinkBlocks(ctx context.Context, client *aipe.AIPEClient, sourceID string, links []DataObjectLinksLinkModel) error {
+	for i, link := range links {
+		linkData, err := client.GetDataObjectLinks(ctx, sourceID, link.LinkName.ValueString(), link.RelationName.ValueString())
+		if err != nil {
+			return err
+		}
+
+		if linkData == nil {
+			links[i].TargetIDs = []string{}
+		} else {
+			links[i].TargetIDs = linkData
+		}
+	}
+	return nil
+}
+
+func (d *DataObjectLinksResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	link := linkBlock()
+	link.Validators = []validator.Set{
+		setvalidator.SizeAtLeast(1),
+	}
+
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Manages the links of several relations of one data object. All changes are applied in a single request, so the object never has only a part of its relations. Each relation is managed authoritatively like in `swp_aipe_data_object_link`",
+		Attributes: map[string]schema.Attribute{
+			"source_id": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "The system.id of the source object",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"link": link,
+		},
+	}
+}
+
+func (r *DataObjectLinksResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*aipe.AIPEClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *aipe.AIPEClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.client = client
+}
+
+func (d *DataObjectLinksResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_aipe_data_object_links"
+}
+
+func (d *DataObjectLinksResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	resp.Diagnostics.Append(validateLinkBlocks(ctx, req.Config)...)
+}
+
+func (d *DataObjectLinksResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data DataObjectLinksResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	links := linkDefinitions(nil, data.Links)
+	if len(links) > 0 {
+		tflog.Info(ctx, "Creating links", map[string]interface{}{"source_id": data.SourceID.ValueString(), "relations": len(links)})
+		err := d.client.UpdateDataObjectLinksBatch(ctx, data.SourceID.ValueString(), links)
+		if err != nil {
+			resp.Diagnostics.Append(clientErrorDiagnostics("Failed to create links", "Unable to create links", err, nil)...)
+			return
+		}
+	} else {
+		tflog.Info(ctx, "No links to create", map[string]interface{}{"source_id": data.SourceID.ValueString()})
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (d *DataObjectLinksResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data DataObjectLinksResourceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := readLinkBlocks(ctx, d.client, data.SourceID.ValueString(), data.Links)
+	if aipe.ErrorIsNotFound(err) {
+		tflog.Info(ctx, "Source object of links not found, removing links from state", map[string]interface{}{"source_id": data.SourceID.ValueString()})
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.Append(clientErrorDiagnostics("Failed to get link data", "Unable to read links", err, nil)...)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (d *DataObjectLinksResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan DataObjectLinksResourceModel
+	var state DataObjectLinksResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	links := linkDefinitions(state.Links, plan.Links)
+	if len(links) == 0 {
+		tflog.Info(ctx, "No changes to links", map[string]interface{}{"source_id": plan.SourceID.ValueString()})
+	} else {
+		tflog.Info(ctx, "Updating links", map[string]interface{}{"source_id": plan.SourceID.ValueString(), "relations": len(links)})
+		err := d.client.UpdateDataObjectLinksBatch(ctx, plan.SourceID.ValueString(), links)
+		if err != nil {
+			resp.Diagnostics.Append(clientErrorDiagnostics("Failed to update links", "Unable to update links", err, nil)...)
+			return
+		}
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (d *DataObjectLinksResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data DataObjectLinksResourceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	links := linkDefinitions(data.Links, nil)
+	if len(links) == 0 {
+		tflog.Info(ctx, "No links to delete", map[string]interface{}{"source_id": data.SourceID.ValueString()})
+		return
+	}
+
+	tflog.Info(ctx, "Deleting links", map[string]interface{}{"source_id": data.SourceID.ValueString(), "relations": len(links)})
+	err := removeLinks(ctx, d.client, data.SourceID.ValueString(), links)
+	if err != nil {
+		resp.Diagnostics.Append(clientErrorDiagnostics("Failed to delete links", "Unable to delete links", err, nil)...)
+	}
+}
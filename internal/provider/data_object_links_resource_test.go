@@ -0,0 +1,156 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/Serviceware/terraform-provider-swp/internal/aipe"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+var backupLinkNameFromAIPE = "server-backed-up-by-hoster"
+var backupRelationNameFromAIPE = "backs-up"
+
+func testLinkBlock(linkName string, relationName string, targetIDs ...string) DataObjectLinksLinkModel {
+	return DataObjectLinksLinkModel{
+		LinkName:     types.StringValue(linkName),
+		RelationName: types.StringValue(relationName),
+		TargetIDs:    targetIDs,
+	}
+}
+
+func TestLinkDefinitions(t *testing.T) {
+	state := []DataObjectLinksLinkModel{
+		testLinkBlock("hosting", "hosts", "a", "b"),
+		testLinkBlock("backup", "backs-up", "c"),
+		testLinkBlock("support", "supports", "d"),
+	}
+	plan := []DataObjectLinksLinkModel{
+		testLinkBlock("hosting", "hosts", "b", "e"),
+		testLinkBlock("backup", "backs-up", "c"),
+		testLinkBlock("monitoring", "monitors", "f"),
+	}
+
+	links := linkDefinitions(state, plan)
+	for i := range links {
+		slices.Sort(links[i].Add)
+		slices.Sort(links[i].Remove)
+	}
+
+	expected := []aipe.LinkDefinition{
+		{LinkName: "hosting", RelationName: "hosts", Add: []string{"e"}, Remove: []string{"a"}},
+		{LinkName: "monitoring", RelationName: "monitors", Add: []string{"f"}},
+		{LinkName: "support", RelationName: "supports", Remove: []string{"d"}},
+	}
+	if !slices.EqualFunc(links, expected, func(a, b aipe.LinkDefinition) bool {
+		return a.LinkName == b.LinkName && a.RelationName == b.RelationName && slices.Equal(a.Add, b.Add) && slices.Equal(a.Remove, b.Remove)
+	}) {
+		t.Errorf("expected %+v, got %+v", expected, links)
+	}
+
+	if links := linkDefinitions(plan, plan); len(links) != 0 {
+		t.Errorf("expected no changes, got %+v", links)
+	}
+}
+
+func TestAccAIPEDataObjectLinks(t *testing.T) {
+	var db01 = &DataObject{}
+	var db02 = &DataObject{}
+	var cloudInc = &DataObject{}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		PreCheck:                 func() { testAccPreCheck(t) },
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataObjectLinks(`
+	link {
+		link_name     = "%[1]s"
+		relation_name = "%[2]s"
+		target_ids    = [swp_aipe_data_object.db01.id, swp_aipe_data_object.db02.id]
+	}
+
+	link {
+		link_name     = "%[3]s"
+		relation_name = "%[4]s"
+		target_ids    = [swp_aipe_data_object.db01.id]
+	}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccDataObjectIDFetch("swp_aipe_data_object.db01", db01),
+					testAccDataObjectIDFetch("swp_aipe_data_object.db02", db02),
+					testAccDataObjectIDFetch("swp_aipe_data_object.cloud_inc", cloudInc),
+					testAccDataObjectHasLinks(cloudInc, linkNameFromAIPE, relationNameFromAIPE, []*DataObject{db01, db02}),
+					testAccDataObjectHasLinks(cloudInc, backupLinkNameFromAIPE, backupRelationNameFromAIPE, []*DataObject{db01}),
+				),
+			},
+			{
+				// the removed relation loses its links
+				Config: testAccDataObjectLinks(`
+	link {
+		link_name     = "%[1]s"
+		relation_name = "%[2]s"
+		target_ids    = [swp_aipe_data_object.db02.id]
+	}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccDataObjectHasLinks(cloudInc, linkNameFromAIPE, relationNameFromAIPE, []*DataObject{db02}),
+					testAccDataObjectHasLinks(cloudInc, backupLinkNameFromAIPE, backupRelationNameFromAIPE, []*DataObject{}),
+				),
+			},
+			{
+				Config: testAccDataObjectLinks(`
+	link {
+		link_name     = "%[1]s"
+		relation_name = "%[2]s"
+		target_ids    = [swp_aipe_data_object.db01.id]
+	}
+
+	link {
+		link_name     = "%[1]s"
+		relation_name = "%[2]s"
+		target_ids    = [swp_aipe_data_object.db02.id]
+	}
+`),
+				ExpectError: regexp.MustCompile("Duplicate relation"),
+			},
+		},
+	})
+}
+
+func testAccDataObjectLinks(links string) string {
+	return fmt.Sprintf(`
+resource "swp_aipe_data_object" "db01" {
+	type = "%s"
+	properties = {
+		"fqdn" = "db01"
+	}
+}
+
+resource "swp_aipe_data_object" "db02" {
+	type = "%s"
+	properties = {
+		"fqdn" = "db02"
+	}
+}
+
+resource "swp_aipe_data_object" "cloud_inc" {
+	type = "%s"
+	properties = {
+		"support-portal" = "https://support.cloud-inc.example"
+	}
+}
+
+resource "swp_aipe_data_object_links" "cloud_inc" {
+	source_id = swp_aipe_data_object.cloud_inc.id
+%s
+}
+`,
+		serverObjectTypeFromAIPE,
+		serverObjectTypeFromAIPE,
+		hosterObjectTypeFromAIPE,
+		fmt.Sprintf(links, linkNameFromAIPE, relationNameFromAIPE, backupLinkNameFromAIPE, backupRelationNameFromAIPE))
+}
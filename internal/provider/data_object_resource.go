@@ -5,14 +5,21 @@ package provider
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"slices"
 
 	"github.com/Serviceware/terraform-provider-swp/internal/aipe"
+	"github.com/hashicorp/terraform-plugin-framework-jsontypes/jsontypes"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
@@ -20,6 +27,7 @@ import (
 
 var _ resource.Resource = &DataObjectResource{}
 var _ resource.ResourceWithImportState = &DataObjectResource{}
+var _ resource.ResourceWithValidateConfig = &DataObjectResource{}
 
 func NewDataObjectResource() resource.Resource {
 	return &DataObjectResource{}
@@ -30,9 +38,152 @@ type DataObjectResource struct {
 }
 
 type DataObjectResourceModel struct {
-	DataObjectType types.String      `tfsdk:"type"`
-	Properties     map[string]string `tfsdk:"properties"`
-	Id             types.String      `tfsdk:"id"`
+	DataObjectType types.String         `tfsdk:"type"`
+	Properties     map[string]string    `tfsdk:"properties"`
+	PropertiesJSON jsontypes.Normalized `tfsdk:"properties_json"`
+	OnRemoval      types.String         `tfsdk:"on_property_removal"`
+	Id             types.String         `tfsdk:"id"`
+	CreatedAt      types.String         `tfsdk:"created_at"`
+	CreatedBy      types.String         `tfsdk:"created_by"`
+	ModifiedAt     types.String         `tfsdk:"modified_at"`
+	Version        types.String         `tfsdk:"version"`
+
+	Links []DataObjectLinksLinkModel `tfsdk:"link"`
+}
+
+// setSystemMetadata copies the system metadata of the object into the model.
+func (m *DataObjectResourceModel) setSystemMetadata(system aipe.SystemMetadata) {
+	if system.TypeName != "" {
+		m.DataObjectType = types.StringValue(system.TypeName)
+	}
+	m.CreatedAt = types.StringValue(system.CreatedAt)
+	m.CreatedBy = types.StringValue(system.CreatedBy)
+	m.ModifiedAt = types.StringValue(system.ModifiedAt)
+	m.Version = types.StringValue(system.Version)
+}
+
+const (
+	// propertyRemovalClear sets properties removed from the configuration to null in the AIPE.
+	propertyRemovalClear = "clear"
+
+	// propertyRemovalKeep leaves properties removed from the configuration untouched in the AIPE.
+	propertyRemovalKeep = "keep"
+)
+
+// typedProperties merges `properties` and `properties_json` into the typed
+// property map sent to the AIPE.
+func (m DataObjectResourceModel) typedProperties() (map[string]interface{}, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	properties := aipe.ConvertPropertiesFromString(m.Properties)
+	if m.PropertiesJSON.IsNull() || m.PropertiesJSON.IsUnknown() {
+		return properties, diags
+	}
+
+	jsonProperties, err := aipe.DecodeProperties([]byte(m.PropertiesJSON.ValueString()))
+	if err != nil {
+		diags.AddAttributeError(path.Root("properties_json"), "Invalid properties_json", fmt.Sprintf("properties_json must be a JSON object: %s", err))
+		return nil, diags
+	}
+
+	for k, v := range jsonProperties {
+		if _, ok := properties[k]; ok {
+			diags.AddAttributeError(path.Root("properties_json"), "Duplicate property", fmt.Sprintf("Property %q is set in both properties and properties_json", k))
+			continue
+		}
+		properties[k] = v
+	}
+	return properties, diags
+}
+
+// privateStateETag is the key of the entity tag in the private state. It is
+// captured whenever the object is read and sent with updates, so changes made
+// in the AIPE after the plan are not overwritten.
+const privateStateETag = "etag"
+
+type privateStateGetter interface {
+	GetKey(ctx context.Context, key string) ([]byte, diag.Diagnostics)
+}
+
+type privateStateSetter interface {
+	SetKey(ctx context.Context, key string, value []byte) diag.Diagnostics
+}
+
+func getETag(ctx context.Context, private privateStateGetter) (string, diag.Diagnostics) {
+	value, diags := private.GetKey(ctx, privateStateETag)
+	if diags.HasError() || len(value) == 0 {
+		return "", diags
+	}
+
+	var etag string
+	if err := json.Unmarshal(value, &etag); err != nil {
+		diags.AddError("Invalid private state", fmt.Sprintf("Unable to parse entity tag from private state: %s", err))
+	}
+	return etag, diags
+}
+
+func setETag(ctx context.Context, private privateStateSetter, etag string) diag.Diagnostics {
+	value, err := json.Marshal(etag)
+	if err != nil {
+		var diags diag.Diagnostics
+		diags.AddError("Invalid private state", fmt.Sprintf("Unable to encode entity tag: %s", err))
+		return diags
+	}
+	return private.SetKey(ctx, privateStateETag, value)
+}
+
+// propertyNames returns the names of all properties managed by the resource.
+func (m DataObjectResourceModel) propertyNames() map[string]bool {
+	names := make(map[string]bool)
+	for k := range m.Properties {
+		names[k] = true
+	}
+
+	if m.PropertiesJSON.IsNull() || m.PropertiesJSON.IsUnknown() {
+		return names
+	}
+	jsonProperties, err := aipe.DecodeProperties([]byte(m.PropertiesJSON.ValueString()))
+	if err != nil {
+		return names
+	}
+	for k := range jsonProperties {
+		names[k] = true
+	}
+	return names
+}
+
+// removedProperties returns the sorted names of the properties which are in
+// the state, but no longer in the plan.
+func removedProperties(state DataObjectResourceModel, plan DataObjectResourceModel) []string {
+	planNames := plan.propertyNames()
+
+	var removed []string
+	for k := range state.propertyNames() {
+		if !planNames[k] {
+			removed = append(removed, k)
+		}
+	}
+	slices.Sort(removed)
+	return removed
+}
+
+// propertyPath returns the attribute path in which the property is configured.
+func (m DataObjectResourceModel) propertyPath(property string) (path.Path, bool) {
+	if _, ok := m.Properties[property]; ok {
+		return path.Root("properties").AtMapKey(property), true
+	}
+
+	if m.PropertiesJSON.IsNull() || m.PropertiesJSON.IsUnknown() {
+		return path.Empty(), false
+	}
+	jsonProperties, err := aipe.DecodeProperties([]byte(m.PropertiesJSON.ValueString()))
+	if err != nil {
+		return path.Empty(), false
+	}
+	if _, ok := jsonProperties[property]; ok {
+		return path.Root("properties_json"), true
+	}
+	return path.Empty(), false
 }
 
 func (r *DataObjectResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -40,18 +191,38 @@ func (r *DataObjectResource) Metadata(ctx context.Context, req resource.Metadata
 }
 
 func (r *DataObjectResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	link := linkBlock()
+	link.MarkdownDescription = "The targets of one relation of the data object, which are linked in the same request that creates or updates the object. Each relation is managed authoritatively like in `swp_aipe_data_object_link` and may only appear once"
+
 	resp.Schema = schema.Schema{
 		MarkdownDescription: "Creates a data object in the AIPE",
 
 		Attributes: map[string]schema.Attribute{
 			"type": schema.StringAttribute{
-				MarkdownDescription: "The data type name ob the object. Must be the internal name (usually-in-lowecase-and-kebabxase)",
+				MarkdownDescription: "The data type name ob the object. Must be the internal name (usually-in-lowecase-and-kebabxase). Changing the type replaces the object",
 				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
 			},
 			"properties": schema.MapAttribute{
 				ElementType:         types.StringType,
-				MarkdownDescription: "The property values for the data object",
+				MarkdownDescription: "The property values for the data object. The strings `true` and `false` are sent as booleans, everything else as string",
+				Optional:            true,
+			},
+			"properties_json": schema.StringAttribute{
+				CustomType:          jsontypes.NormalizedType{},
+				MarkdownDescription: "The property values for the data object as JSON object, usually built with `jsonencode`. Use this for numbers, null, lists, nested objects or strings which must not be converted to booleans",
+				Optional:            true,
+			},
+			"on_property_removal": schema.StringAttribute{
+				MarkdownDescription: "What happens to a property in the AIPE when it is removed from `properties` or `properties_json`. `clear` sets it to null, `keep` leaves the last value in place. Defaults to `clear`",
 				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString(propertyRemovalClear),
+				Validators: []validator.String{
+					stringvalidator.OneOf(propertyRemovalClear, propertyRemovalKeep),
+				},
 			},
 			"id": schema.StringAttribute{
 				Computed:            true,
@@ -60,6 +231,32 @@ func (r *DataObjectResource) Schema(ctx context.Context, req resource.SchemaRequ
 					stringplanmodifier.UseStateForUnknown(),
 				},
 			},
+			"created_at": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "When the data object was created",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"created_by": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Who created the data object",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"modified_at": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "When the data object was modified the last time",
+			},
+			"version": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "The version of the data object, which changes with every modification",
+			},
+		},
+
+		Blocks: map[string]schema.Block{
+			"link": link,
 		},
 	}
 }
@@ -83,6 +280,31 @@ func (r *DataObjectResource) Configure(ctx context.Context, req resource.Configu
 	r.client = client
 }
 
+func (r *DataObjectResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var properties types.Map
+	var propertiesJSON jsontypes.Normalized
+
+	resp.Diagnostics.Append(validateLinkBlocks(ctx, req.Config)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("properties"), &properties)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("properties_json"), &propertiesJSON)...)
+
+	if resp.Diagnostics.HasError() || propertiesJSON.IsNull() || propertiesJSON.IsUnknown() {
+		return
+	}
+
+	jsonProperties, err := aipe.DecodeProperties([]byte(propertiesJSON.ValueString()))
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("properties_json"), "Invalid properties_json", fmt.Sprintf("properties_json must be a JSON object: %s", err))
+		return
+	}
+
+	for k := range properties.Elements() {
+		if _, ok := jsonProperties[k]; ok {
+			resp.Diagnostics.AddAttributeError(path.Root("properties_json"), "Duplicate property", fmt.Sprintf("Property %q is set in both properties and properties_json", k))
+		}
+	}
+}
+
 func (r *DataObjectResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
 	var data DataObjectResourceModel
 
@@ -92,13 +314,30 @@ func (r *DataObjectResource) Create(ctx context.Context, req resource.CreateRequ
 		return
 	}
 
-	id, err := r.client.CreateObject(ctx, data.DataObjectType.ValueString(), data.Properties)
+	properties, diags := data.typedProperties()
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	id, err := r.client.CreateObjectWithLinks(ctx, data.DataObjectType.ValueString(), properties, linkDefinitions(nil, data.Links))
 	if err != nil {
-		resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to create example, got error: %s", err))
+		resp.Diagnostics.Append(clientErrorDiagnostics("Client Error", "Unable to create example", err, data.propertyPath)...)
 		return
 	}
 	data.Id = basetypes.NewStringValue(id)
 
+	// The object exists at this point, so it is stored in the state even if
+	// reading its metadata fails. Terraform then marks it as tainted.
+	object, err := r.client.GetObject(ctx, id)
+	if err != nil {
+		resp.Diagnostics.Append(clientErrorDiagnostics("Client Error", "Unable to read created example", err, nil)...)
+		data.setSystemMetadata(aipe.SystemMetadata{})
+	} else {
+		data.setSystemMetadata(object.System)
+		resp.Diagnostics.Append(setETag(ctx, resp.Private, object.ETag)...)
+	}
+
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
@@ -119,21 +358,52 @@ func (r *DataObjectResource) Read(ctx context.Context, req resource.ReadRequest,
 			resp.State.RemoveResource(ctx)
 			return
 		}
-		resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to read data source, got error: %s", err))
+		resp.Diagnostics.Append(clientErrorDiagnostics("Client Error", "Unable to read data source", err, nil)...)
 		return
 	}
-	tflog.Info(ctx, "Successfully read data source", map[string]interface{}{"object": object, "error": err})
-
-	tflog.Debug(ctx, "Properties", map[string]interface{}{"properties": data.Properties})
+	tflog.Info(ctx, "Successfully read data source", map[string]interface{}{"id": object.ID, "version": object.System.Version})
 
 	// We only copy the properties the user cares about into our resource.
 	// This enables partial object management.
+	data.setSystemMetadata(object.System)
+	resp.Diagnostics.Append(setETag(ctx, resp.Private, object.ETag)...)
+
 	for k := range data.Properties {
-		if v, ok := object[k]; ok {
-			data.Properties[k] = v
+		if v, ok := object.Properties[k]; ok && v != nil {
+			data.Properties[k] = aipe.ConvertPropertyToString(v)
 		}
 	}
 
+	if !data.PropertiesJSON.IsNull() {
+		jsonProperties, err := aipe.DecodeProperties([]byte(data.PropertiesJSON.ValueString()))
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("properties_json"), "Invalid properties_json", fmt.Sprintf("Unable to parse properties_json from state: %s", err))
+			return
+		}
+		for k := range jsonProperties {
+			if v, ok := object.Properties[k]; ok {
+				jsonProperties[k] = v
+			}
+		}
+		encoded, err := json.Marshal(jsonProperties)
+		if err != nil {
+			resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to encode properties_json, got error: %s", err))
+			return
+		}
+		data.PropertiesJSON = jsontypes.NewNormalizedValue(string(encoded))
+	}
+
+	err = readLinkBlocks(ctx, r.client, data.Id.ValueString(), data.Links)
+	if err != nil {
+		resp.Diagnostics.Append(clientErrorDiagnostics("Client Error", "Unable to read links of data source", err, nil)...)
+		return
+	}
+
+	// Imported resources have no value yet
+	if data.OnRemoval.IsNull() {
+		data.OnRemoval = types.StringValue(propertyRemovalClear)
+	}
+
 	tflog.Trace(ctx, "read a data source")
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
@@ -151,12 +421,45 @@ func (r *DataObjectResource) Update(ctx context.Context, req resource.UpdateRequ
 		return
 	}
 
-	tflog.Info(ctx, "Updating data source", map[string]interface{}{"plan": plan, "state": state})
-	err := r.client.UpdateObject(ctx, state.Id.ValueString(), plan.Properties)
+	tflog.Info(ctx, "Updating data source", map[string]interface{}{"id": state.Id.ValueString()})
+	properties, diags := plan.typedProperties()
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if plan.OnRemoval.ValueString() != propertyRemovalKeep {
+		for _, k := range removedProperties(state, plan) {
+			properties[k] = nil
+		}
+	}
+
+	etag, diags := getETag(ctx, req.Private)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := r.client.UpdateObjectWithLinks(ctx, state.Id.ValueString(), properties, linkDefinitions(state.Links, plan.Links), etag)
+	if aipe.ErrorIsConflict(err) {
+		resp.Diagnostics.AddError(
+			"Object changed since plan",
+			fmt.Sprintf("The data object %s has been modified in the AIPE after the plan was created. The update was not applied to avoid overwriting these changes, run terraform apply again to plan against the current object.", state.Id.ValueString()),
+		)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.Append(clientErrorDiagnostics("Client Error", "Unable to update example", err, plan.propertyPath)...)
+		return
+	}
+
+	object, err := r.client.GetObject(ctx, state.Id.ValueString())
 	if err != nil {
-		resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to update example, got error: %s", err))
+		resp.Diagnostics.Append(clientErrorDiagnostics("Client Error", "Unable to read updated example", err, nil)...)
 		return
 	}
+	plan.setSystemMetadata(object.System)
+	resp.Diagnostics.Append(setETag(ctx, resp.Private, object.ETag)...)
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
 }
@@ -176,7 +479,7 @@ func (r *DataObjectResource) Delete(ctx context.Context, req resource.DeleteRequ
 		if aipe.ErrorIsNotFound(err) {
 			return
 		}
-		resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to delete example, got error: %s", err))
+		resp.Diagnostics.Append(clientErrorDiagnostics("Client Error", "Unable to delete example", err, nil)...)
 	}
 }
 
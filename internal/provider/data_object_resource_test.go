@@ -0,0 +1,275 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework-jsontypes/jsontypes"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/plancheck"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+var secondProperty = "bar"
+
+var removedPropertiesTests = []struct {
+	state    DataObjectResourceModel
+	plan     DataObjectResourceModel
+	expected []string
+}{
+	{
+		state:    DataObjectResourceModel{Properties: map[string]string{"a": "1", "b": "2"}},
+		plan:     DataObjectResourceModel{Properties: map[string]string{"a": "1"}},
+		expected: []string{"b"},
+	},
+	{
+		state:    DataObjectResourceModel{Properties: map[string]string{"a": "1"}, PropertiesJSON: jsontypes.NewNormalizedValue(`{"b":2,"c":null}`)},
+		plan:     DataObjectResourceModel{Properties: map[string]string{"a": "1"}, PropertiesJSON: jsontypes.NewNormalizedNull()},
+		expected: []string{"b", "c"},
+	},
+	{
+		// moving a property between both attributes does not remove it
+		state:    DataObjectResourceModel{Properties: map[string]string{"a": "1"}},
+		plan:     DataObjectResourceModel{PropertiesJSON: jsontypes.NewNormalizedValue(`{"a":1}`)},
+		expected: nil,
+	},
+	{
+		state:    DataObjectResourceModel{},
+		plan:     DataObjectResourceModel{Properties: map[string]string{"a": "1"}},
+		expected: nil,
+	},
+}
+
+func TestRemovedProperties(t *testing.T) {
+	for _, tt := range removedPropertiesTests {
+		t.Run(fmt.Sprintf("%v", tt.expected), func(t *testing.T) {
+			removed := removedProperties(tt.state, tt.plan)
+			if !slices.Equal(removed, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, removed)
+			}
+		})
+	}
+}
+
+func TestAccAIPEDataObjectPropertyRemoval(t *testing.T) {
+	var cleared = &DataObject{}
+	var kept = &DataObject{}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		PreCheck:                 func() { testAccPreCheck(t) },
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataObjectPropertyRemoval(fmt.Sprintf(`%s = "bar"`, secondProperty)),
+				Check: resource.ComposeTestCheckFunc(
+					testAccDataObjectIDFetch("swp_aipe_data_object.cleared", cleared),
+					testAccDataObjectIDFetch("swp_aipe_data_object.kept", kept),
+					resource.TestCheckResourceAttr("swp_aipe_data_object.cleared", "on_property_removal", "clear"),
+				),
+			},
+			{
+				Config: testAccDataObjectPropertyRemoval(""),
+				Check: resource.ComposeTestCheckFunc(
+					testAccDataObjectHasProperty(cleared, secondProperty, false),
+					testAccDataObjectHasProperty(kept, secondProperty, true),
+					testAccDataObjectHasProperty(kept, existingProperty, true),
+				),
+			},
+		},
+	})
+}
+
+func TestAccAIPEDataObjectChangedSincePlan(t *testing.T) {
+	var object = &DataObject{}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		PreCheck:                 func() { testAccPreCheck(t) },
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataObjectChangedSincePlan("planned"),
+				Check:  testAccDataObjectIDFetch("swp_aipe_data_object.changed", object),
+			},
+			{
+				// the refresh of the plan picks up changes made before it, so
+				// the object is modified between plan and apply
+				Config: testAccDataObjectChangedSincePlan("applied"),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						testAccDataObjectModify{object: object, properties: map[string]interface{}{existingProperty: "modified"}},
+					},
+				},
+				ExpectError: regexp.MustCompile("Object changed since plan"),
+			},
+		},
+	})
+}
+
+// testAccDataObjectModify is a plan check which modifies the data object in
+// the AIPE after the plan has been created.
+type testAccDataObjectModify struct {
+	object     *DataObject
+	properties map[string]interface{}
+}
+
+func (m testAccDataObjectModify) CheckPlan(ctx context.Context, req plancheck.CheckPlanRequest, resp *plancheck.CheckPlanResponse) {
+	resp.Error = aipeClient.UpdateObject(ctx, m.object.ID, m.properties, "")
+}
+
+func testAccDataObjectChangedSincePlan(value string) string {
+	return fmt.Sprintf(`
+resource "swp_aipe_data_object" "changed" {
+	type = "test-object"
+	properties = {
+		%s = "%s"
+	}
+}
+`, existingProperty, value)
+}
+
+func TestAccAIPEDataObjectTypeChange(t *testing.T) {
+	resourceName := "swp_aipe_data_object.typed"
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		PreCheck:                 func() { testAccPreCheck(t) },
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataObjectTyped("test-object", existingProperty),
+			},
+			// Imported objects get their type from the AIPE
+			{
+				ResourceName:            resourceName,
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"properties"},
+			},
+			{
+				Config: testAccDataObjectTyped(serverObjectTypeFromAIPE, "fqdn"),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction(resourceName, plancheck.ResourceActionDestroyBeforeCreate),
+					},
+				},
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(resourceName, "type", serverObjectTypeFromAIPE),
+				),
+			},
+		},
+	})
+}
+
+func TestAccAIPEDataObjectInlineLinks(t *testing.T) {
+	var db01 = &DataObject{}
+	var db02 = &DataObject{}
+	var cloudInc = &DataObject{}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		PreCheck:                 func() { testAccPreCheck(t) },
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataObjectInlineLinks("db01"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccDataObjectIDFetch("swp_aipe_data_object.db01", db01),
+					testAccDataObjectIDFetch("swp_aipe_data_object.db02", db02),
+					testAccDataObjectIDFetch("swp_aipe_data_object.cloud_inc", cloudInc),
+					testAccDataObjectHasLinks(cloudInc, linkNameFromAIPE, relationNameFromAIPE, []*DataObject{db01}),
+				),
+			},
+			{
+				Config: testAccDataObjectInlineLinks("db02"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccDataObjectHasLinks(cloudInc, linkNameFromAIPE, relationNameFromAIPE, []*DataObject{db02}),
+					resource.TestCheckResourceAttr("swp_aipe_data_object.cloud_inc", "properties.name", "Cloud Inc."),
+				),
+			},
+		},
+	})
+}
+
+func testAccDataObjectInlineLinks(target string) string {
+	return fmt.Sprintf(`
+resource "swp_aipe_data_object" "db01" {
+	type = "%[1]s"
+	properties = {
+		"fqdn" = "db01"
+	}
+}
+
+resource "swp_aipe_data_object" "db02" {
+	type = "%[1]s"
+	properties = {
+		"fqdn" = "db02"
+	}
+}
+
+resource "swp_aipe_data_object" "cloud_inc" {
+	type = "%[2]s"
+	properties = {
+		"name" = "Cloud Inc."
+	}
+
+	link {
+		link_name     = "%[3]s"
+		relation_name = "%[4]s"
+		target_ids    = [swp_aipe_data_object.%[5]s.id]
+	}
+}
+`,
+		serverObjectTypeFromAIPE,
+		hosterObjectTypeFromAIPE,
+		linkNameFromAIPE,
+		relationNameFromAIPE,
+		target)
+}
+
+func testAccDataObjectTyped(objectType string, propertyName string) string {
+	return fmt.Sprintf(`
+resource "swp_aipe_data_object" "typed" {
+	type = "%s"
+	properties = {
+		%s = "typed"
+	}
+}
+`, objectType, propertyName)
+}
+
+func testAccDataObjectHasProperty(object *DataObject, property string, expected bool) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		dataObject, err := aipeClient.GetObject(context.Background(), object.ID)
+		if err != nil {
+			return err
+		}
+
+		properties := dataObject.Properties
+		if value, ok := properties[property]; (ok && value != nil) != expected {
+			return fmt.Errorf("expected property %s to be present: %t, got %v", property, expected, properties)
+		}
+		return nil
+	}
+}
+
+func testAccDataObjectPropertyRemoval(extraProperty string) string {
+	return fmt.Sprintf(`
+resource "swp_aipe_data_object" "cleared" {
+	type = "test-object"
+	properties = {
+		%[1]s = "foo"
+		%[2]s
+	}
+}
+
+resource "swp_aipe_data_object" "kept" {
+	type = "test-object"
+	on_property_removal = "keep"
+	properties = {
+		%[1]s = "foo"
+		%[2]s
+	}
+}
+`, existingProperty, extraProperty)
+}
@@ -0,0 +1,173 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Serviceware/terraform-provider-swp/internal/aipe"
+	"github.com/hashicorp/terraform-plugin-framework-jsontypes/jsontypes"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &DataObjectsDataSource{}
+
+func NewDataObjectsDataSource() datasource.DataSource {
+	return &DataObjectsDataSource{}
+}
+
+type DataObjectsDataSource struct {
+	client *aipe.AIPEClient
+}
+
+type DataObjectsDataSourceModel struct {
+	DataObjectType types.String              `tfsdk:"type"`
+	Filters        map[string]string         `tfsdk:"filters"`
+	Query          types.String              `tfsdk:"query"`
+	Sort           []string                  `tfsdk:"sort"`
+	Limit          types.Int64               `tfsdk:"limit"`
+	Ids            []string                  `tfsdk:"ids"`
+	Objects        []DataObjectsObjectsModel `tfsdk:"objects"`
+}
+
+type DataObjectsObjectsModel struct {
+	Id             types.String         `tfsdk:"id"`
+	Properties     map[string]string    `tfsdk:"properties"`
+	PropertiesJSON jsontypes.Normalized `tfsdk:"properties_json"`
+}
+
+func (d *DataObjectsDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_aipe_data_objects"
+}
+
+func (d *DataObjectsDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Searches data objects of one type in the AIPE",
+
+		Attributes: map[string]schema.Attribute{
+			"type": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "The data type name of the objects. Must be the internal name (usually-in-lowercase-and-kebabcase)",
+			},
+			"filters": schema.MapAttribute{
+				ElementType:         types.StringType,
+				Optional:            true,
+				MarkdownDescription: "Only return objects whose properties have exactly these values",
+			},
+			"query": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "A query expression which is passed to the AIPE as is",
+			},
+			"sort": schema.ListAttribute{
+				ElementType:         types.StringType,
+				Optional:            true,
+				MarkdownDescription: "Sort orders in the form `property` or `property,desc`",
+			},
+			"limit": schema.Int64Attribute{
+				Optional:            true,
+				MarkdownDescription: "The maximum number of objects to return. All matching objects are returned if not set",
+			},
+			"ids": schema.ListAttribute{
+				ElementType:         types.StringType,
+				Computed:            true,
+				MarkdownDescription: "The system.ids of the matching objects",
+			},
+			"objects": schema.ListNestedAttribute{
+				Computed:            true,
+				MarkdownDescription: "The matching objects",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"id": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "The system.id of the data object",
+						},
+						"properties": schema.MapAttribute{
+							ElementType:         types.StringType,
+							Computed:            true,
+							MarkdownDescription: "The properties in the AIPE, as strings. Values which are neither strings nor booleans are rendered as JSON",
+						},
+						"properties_json": schema.StringAttribute{
+							CustomType:          jsontypes.NormalizedType{},
+							Computed:            true,
+							MarkdownDescription: "The properties in the AIPE as JSON object, with their original types. Use `jsondecode` to access them",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *DataObjectsDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*aipe.AIPEClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *aipe.AIPEClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.client = client
+}
+
+func (d *DataObjectsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data DataObjectsDataSourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.Limit.ValueInt64() < 0 {
+		resp.Diagnostics.AddAttributeError(path.Root("limit"), "Invalid limit", "limit must not be negative")
+		return
+	}
+
+	query := aipe.ObjectQuery{
+		TypeName: data.DataObjectType.ValueString(),
+		Filters:  data.Filters,
+		Query:    data.Query.ValueString(),
+		Sort:     data.Sort,
+		Limit:    int(data.Limit.ValueInt64()),
+	}
+
+	tflog.Info(ctx, "Searching data objects", map[string]interface{}{"type": query.TypeName})
+	objects, err := d.client.SearchObjects(ctx, query)
+	if err != nil {
+		resp.Diagnostics.Append(clientErrorDiagnostics("Client Error", "Unable to search data objects", err, nil)...)
+		return
+	}
+	tflog.Info(ctx, "Successfully searched data objects", map[string]interface{}{"count": len(objects)})
+
+	data.Ids = []string{}
+	data.Objects = []DataObjectsObjectsModel{}
+	for _, object := range objects {
+		encoded, err := json.Marshal(object.Properties)
+		if err != nil {
+			resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to encode properties, got error: %s", err))
+			return
+		}
+
+		data.Ids = append(data.Ids, object.ID)
+		data.Objects = append(data.Objects, DataObjectsObjectsModel{
+			Id:             types.StringValue(object.ID),
+			Properties:     aipe.ConvertPropertiesToString(object.Properties),
+			PropertiesJSON: jsontypes.NewNormalizedValue(string(encoded)),
+		})
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
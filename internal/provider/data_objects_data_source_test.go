@@ -0,0 +1,61 @@
+package provider
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccAIPEDataObjectsDataSource(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		PreCheck:                 func() { testAccPreCheck(t) },
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataObjectsDataSource(),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.swp_aipe_data_objects.by_fqdn", "ids.#", "1"),
+					resource.TestCheckResourceAttrPair("data.swp_aipe_data_objects.by_fqdn", "ids.0", "swp_aipe_data_object.search_db02", "id"),
+					resource.TestCheckResourceAttr("data.swp_aipe_data_objects.by_fqdn", "objects.0.properties.fqdn", "search-db02"),
+					resource.TestCheckResourceAttr("data.swp_aipe_data_objects.first", "ids.#", "1"),
+				),
+			},
+		},
+	})
+}
+
+func testAccDataObjectsDataSource() string {
+	return fmt.Sprintf(`
+resource "swp_aipe_data_object" "search_db01" {
+	type = "%[1]s"
+	properties = {
+		"fqdn" = "search-db01"
+	}
+}
+
+resource "swp_aipe_data_object" "search_db02" {
+	type = "%[1]s"
+	properties = {
+		"fqdn" = "search-db02"
+	}
+}
+
+data "swp_aipe_data_objects" "by_fqdn" {
+	type = "%[1]s"
+	filters = {
+		"fqdn" = "search-db02"
+	}
+
+	depends_on = [swp_aipe_data_object.search_db01, swp_aipe_data_object.search_db02]
+}
+
+data "swp_aipe_data_objects" "first" {
+	type  = "%[1]s"
+	sort  = ["fqdn"]
+	limit = 1
+
+	depends_on = [swp_aipe_data_object.search_db01, swp_aipe_data_object.search_db02]
+}
+`, serverObjectTypeFromAIPE)
+}
@@ -0,0 +1,64 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Serviceware/terraform-provider-swp/internal/aipe"
+	"github.com/Serviceware/terraform-provider-swp/internal/authenticator"
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+// clientErrorDiagnostics renders an error returned by the AIPE client. Failed
+// logins and tokens lacking the configured scopes or audience are reported as
+// a problem of the provider configuration. Field
+// errors of an [aipe.ApiError] are additionally reported on the attribute
+// returned by attributeFor, so Terraform can point at the offending property.
+// attributeFor may be nil if the request did not send any properties.
+func clientErrorDiagnostics(summary string, detail string, err error, attributeFor func(field string) (path.Path, bool)) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	authError, ok := errwrap.GetType(err, &authenticator.AuthError{}).(*authenticator.AuthError)
+	if ok && authError != nil && authError.StatusCode < http.StatusInternalServerError {
+		diags.AddError(
+			"Invalid provider configuration",
+			fmt.Sprintf("%s, the Authenticator rejected the login: %s\nCheck the credentials in the provider configuration.", detail, authError),
+		)
+		return diags
+	}
+
+	claimError, ok := errwrap.GetType(err, &authenticator.ClaimError{}).(*authenticator.ClaimError)
+	if ok && claimError != nil {
+		diags.AddError(
+			"Invalid provider configuration",
+			fmt.Sprintf("%s, the Authenticator issued a token which does not match the provider configuration: %s\nCheck the scopes and audience in the provider configuration.", detail, claimError),
+		)
+		return diags
+	}
+
+	apiError, ok := errwrap.GetType(err, &aipe.ApiError{}).(*aipe.ApiError)
+	if !ok || apiError == nil || attributeFor == nil {
+		diags.AddError(summary, fmt.Sprintf("%s, got error: %s", detail, err))
+		return diags
+	}
+
+	reported := 0
+	for _, fieldError := range apiError.FieldErrors {
+		if attributePath, ok := attributeFor(fieldError.Field); ok {
+			fieldDetail := fmt.Sprintf("%s, got error: %s: %s", detail, apiError.Message, fieldError)
+			if apiError.TraceID != "" {
+				fieldDetail += fmt.Sprintf("\n(trace id: %s)", apiError.TraceID)
+			}
+			diags.AddAttributeError(attributePath, summary, fieldDetail)
+			reported++
+		}
+	}
+
+	if reported < len(apiError.FieldErrors) || reported == 0 {
+		diags.AddError(summary, fmt.Sprintf("%s, got error: %s", detail, err))
+	}
+
+	return diags
+}
@@ -0,0 +1,87 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Serviceware/terraform-provider-swp/internal/aipe"
+	"github.com/Serviceware/terraform-provider-swp/internal/authenticator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+func TestClientErrorDiagnosticsFieldErrors(t *testing.T) {
+	model := DataObjectResourceModel{
+		Properties: map[string]string{"oof": "bar"},
+	}
+	err := &aipe.ApiError{
+		StatusCode: 400,
+		Message:    "unexpected status code: 400",
+		FieldErrors: []aipe.FieldError{
+			{Field: "oof", Message: "unknown property"},
+		},
+	}
+
+	diags := clientErrorDiagnostics("Client Error", "Unable to create example", err, model.propertyPath)
+
+	if len(diags) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %d: %v", len(diags), diags)
+	}
+	withPath, ok := diags[0].(diag.DiagnosticWithPath)
+	if !ok {
+		t.Fatalf("expected diagnostic with path, got %T", diags[0])
+	}
+	if !withPath.Path().Equal(path.Root("properties").AtMapKey("oof")) {
+		t.Errorf("unexpected path %s", withPath.Path())
+	}
+}
+
+func TestClientErrorDiagnosticsUnknownField(t *testing.T) {
+	model := DataObjectResourceModel{
+		Properties: map[string]string{"foo": "bar"},
+	}
+	err := &aipe.ApiError{
+		StatusCode: 400,
+		Message:    "unexpected status code: 400",
+		FieldErrors: []aipe.FieldError{
+			{Field: "oof", Message: "unknown property"},
+		},
+	}
+
+	diags := clientErrorDiagnostics("Client Error", "Unable to create example", err, model.propertyPath)
+
+	if len(diags) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %d: %v", len(diags), diags)
+	}
+	if _, ok := diags[0].(diag.DiagnosticWithPath); ok {
+		t.Errorf("expected diagnostic without path")
+	}
+}
+
+func TestClientErrorDiagnosticsOtherErrors(t *testing.T) {
+	diags := clientErrorDiagnostics("Client Error", "Unable to read example", errors.New("connection refused"), nil)
+
+	if len(diags) != 1 || diags[0].Detail() != "Unable to read example, got error: connection refused" {
+		t.Errorf("unexpected diagnostics %v", diags)
+	}
+}
+
+func TestClientErrorDiagnosticsAuthError(t *testing.T) {
+	err := &authenticator.AuthError{StatusCode: 401, Code: "invalid_client", Description: "Invalid client secret"}
+	diags := clientErrorDiagnostics("Client Error", "Unable to read example", err, nil)
+
+	if len(diags) != 1 || diags[0].Summary() != "Invalid provider configuration" || !strings.Contains(diags[0].Detail(), "invalid_client: Invalid client secret") {
+		t.Errorf("unexpected diagnostics %v", diags)
+	}
+}
+
+func TestClientErrorDiagnosticsClaimError(t *testing.T) {
+	err := fmt.Errorf("unable to get token: %w", &authenticator.ClaimError{Claim: "scope", Missing: []string{"aipe:admin"}, Granted: []string{"aipe:read"}})
+	diags := clientErrorDiagnostics("Client Error", "Unable to read example", err, nil)
+
+	if len(diags) != 1 || diags[0].Summary() != "Invalid provider configuration" || !strings.Contains(diags[0].Detail(), "requested scopes aipe:admin, got: aipe:read") {
+		t.Errorf("unexpected diagnostics %v", diags)
+	}
+}
@@ -0,0 +1,82 @@
+package provider
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+// defaultRequestTimeout is the timeout of a single HTTP request.
+const defaultRequestTimeout = 10 * time.Second
+
+// newHTTPClient creates the HTTP client shared by the AIPE and the
+// authenticator client from the transport settings of the provider.
+func newHTTPClient(data AIPEProviderModel) (*http.Client, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	timeout := defaultRequestTimeout
+	if data.RequestTimeout.ValueString() != "" {
+		var err error
+		timeout, err = time.ParseDuration(data.RequestTimeout.ValueString())
+		if err != nil || timeout <= 0 {
+			diags.AddAttributeError(path.Root("request_timeout"), "request_timeout", fmt.Sprintf("request_timeout must be a positive duration like 30s, got: %q", data.RequestTimeout.ValueString()))
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if data.ProxyURL.ValueString() != "" {
+		proxyURL, err := url.Parse(data.ProxyURL.ValueString())
+		if err != nil || proxyURL.Host == "" {
+			diags.AddAttributeError(path.Root("proxy_url"), "proxy_url", fmt.Sprintf("proxy_url must be a URL like http://proxy:3128, got: %q", data.ProxyURL.ValueString()))
+		} else {
+			transport.Proxy = http.ProxyURL(proxyURL)
+		}
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: data.InsecureSkipVerify.ValueBool(),
+	}
+
+	caCert := []byte(data.CACertPEM.ValueString())
+	if data.CACertFile.ValueString() != "" {
+		var err error
+		caCert, err = os.ReadFile(data.CACertFile.ValueString())
+		if err != nil {
+			diags.AddAttributeError(path.Root("ca_cert_file"), "ca_cert_file", fmt.Sprintf("Unable to read CA certificate: %s", err))
+		}
+	}
+	if len(caCert) > 0 {
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil {
+			rootCAs = x509.NewCertPool()
+		}
+		if !rootCAs.AppendCertsFromPEM(caCert) {
+			diags.AddError("ca_cert_pem", "The CA certificate contains no PEM encoded certificate")
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+
+	if data.ClientCert.ValueString() != "" || data.ClientKey.ValueString() != "" {
+		certificate, err := tls.X509KeyPair([]byte(data.ClientCert.ValueString()), []byte(data.ClientKey.ValueString()))
+		if err != nil {
+			diags.AddAttributeError(path.Root("client_cert"), "client_cert", fmt.Sprintf("Unable to load client certificate: %s", err))
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+	}, diags
+}
@@ -0,0 +1,129 @@
+package provider
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func serverCertPEM(server *httptest.Server) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+}
+
+// selfSignedClientCert returns a PEM encoded client certificate and its key.
+func selfSignedClientCert(t *testing.T) (*x509.Certificate, string, string) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "terraform"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	certificate, _ := x509.ParseCertificate(der)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+
+	return certificate,
+		string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+}
+
+func TestNewHTTPClientTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(server.Close)
+
+	for name, tc := range map[string]struct {
+		data     AIPEProviderModel
+		expected bool
+	}{
+		"unknown CA":  {data: AIPEProviderModel{}, expected: false},
+		"CA from PEM": {data: AIPEProviderModel{CACertPEM: types.StringValue(serverCertPEM(server))}, expected: true},
+		"insecure":    {data: AIPEProviderModel{InsecureSkipVerify: types.BoolValue(true)}, expected: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			client, diags := newHTTPClient(tc.data)
+			if diags.HasError() {
+				t.Fatalf("unexpected diagnostics %v", diags)
+			}
+
+			_, err := client.Get(server.URL)
+			if (err == nil) != tc.expected {
+				t.Errorf("expected success %t, got %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestNewHTTPClientMutualTLS(t *testing.T) {
+	certificate, certPEM, keyPEM := selfSignedClientCert(t)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(certificate)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	client, diags := newHTTPClient(AIPEProviderModel{
+		CACertPEM:  types.StringValue(serverCertPEM(server)),
+		ClientCert: types.StringValue(certPEM),
+		ClientKey:  types.StringValue(keyPEM),
+	})
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics %v", diags)
+	}
+
+	if _, err := client.Get(server.URL); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func TestNewHTTPClientInvalidSettings(t *testing.T) {
+	for name, data := range map[string]AIPEProviderModel{
+		"timeout":     {RequestTimeout: types.StringValue("ten seconds")},
+		"proxy":       {ProxyURL: types.StringValue("proxy:3128")},
+		"CA":          {CACertPEM: types.StringValue("no certificate")},
+		"CA file":     {CACertFile: types.StringValue("/does/not/exist.pem")},
+		"client cert": {ClientCert: types.StringValue("no certificate"), ClientKey: types.StringValue("no key")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, diags := newHTTPClient(data); !diags.HasError() {
+				t.Errorf("expected diagnostics")
+			}
+		})
+	}
+}
+
+func TestNewHTTPClientSettings(t *testing.T) {
+	client, diags := newHTTPClient(AIPEProviderModel{
+		RequestTimeout: types.StringValue("1m"),
+		ProxyURL:       types.StringValue("http://proxy:3128"),
+	})
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics %v", diags)
+	}
+
+	if client.Timeout != time.Minute {
+		t.Errorf("expected timeout of 1m, got %s", client.Timeout)
+	}
+	request, _ := http.NewRequest(http.MethodGet, "https://aipe.example", nil)
+	proxyURL, _ := client.Transport.(*http.Transport).Proxy(request)
+	if proxyURL == nil || proxyURL.String() != "http://proxy:3128" {
+		t.Errorf("expected proxy http://proxy:3128, got %v", proxyURL)
+	}
+}
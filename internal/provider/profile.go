@@ -0,0 +1,134 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// defaultProfile is used if no profile is selected and the configuration file has one with this name.
+const defaultProfile = "default"
+
+// profile holds the settings of a named profile in the shared configuration
+// file. Each profile is a TOML table named after the profile:
+//
+//	[dev]
+//	aipe_url                = "https://aipe.dev.example"
+//	authenticator_realm_url = "https://auth.dev.example/realms/dev"
+//	application_username    = "terraform"
+//	application_password    = "env:SWP_DEV_SECRET"
+//
+// Secrets can reference an environment variable with `env:NAME` or a file
+// with `file:PATH` instead of containing the secret itself.
+type profile struct {
+	AIPEURL               string   `toml:"aipe_url"`
+	AuthenticatorRealmURL string   `toml:"authenticator_realm_url"`
+	TokenURL              string   `toml:"token_url"`
+	AuthMethod            string   `toml:"auth_method"`
+	ApplicationUsername   string   `toml:"application_username"`
+	ApplicationPassword   string   `toml:"application_password"`
+	PrivateKeyFile        string   `toml:"private_key_file"`
+	PrivateKeyID          string   `toml:"private_key_id"`
+	Username              string   `toml:"username"`
+	Password              string   `toml:"password"`
+	AccessToken           string   `toml:"access_token"`
+	CredentialCommand     []string `toml:"credential_command"`
+	Scopes                []string `toml:"scopes"`
+	Audience              string   `toml:"audience"`
+	CACertFile            string   `toml:"ca_cert_file"`
+	ProxyURL              string   `toml:"proxy_url"`
+}
+
+// configFilePath returns the path of the shared configuration file, which is
+// SWP_CONFIG_FILE or swp/config in the XDG configuration directory.
+func configFilePath() (string, error) {
+	if configFile := os.Getenv("SWP_CONFIG_FILE"); configFile != "" {
+		return configFile, nil
+	}
+
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		configHome = filepath.Join(home, ".config")
+	}
+	return filepath.Join(configHome, "swp", "config"), nil
+}
+
+// loadProfile reads the profile from the shared configuration file. If no
+// profile name is given, the default profile is used if there is one, and an
+// empty profile otherwise.
+func loadProfile(name string) (*profile, error) {
+	path, err := configFilePath()
+	if err != nil {
+		return nil, err
+	}
+
+	var profiles map[string]profile
+	metadata, err := toml.DecodeFile(path, &profiles)
+	if os.IsNotExist(err) && name == "" {
+		return &profile{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to read configuration file %s: %w", path, err)
+	}
+
+	if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, 0, len(undecoded))
+		for _, key := range undecoded {
+			keys = append(keys, key.String())
+		}
+		return nil, fmt.Errorf("configuration file %s has unknown settings %s", path, strings.Join(keys, ", "))
+	}
+
+	selected := name
+	if selected == "" {
+		selected = defaultProfile
+	}
+	p, ok := profiles[selected]
+	if !ok {
+		if name == "" {
+			return &profile{}, nil
+		}
+		names := make([]string, 0, len(profiles))
+		for profileName := range profiles {
+			names = append(names, profileName)
+		}
+		slices.Sort(names)
+		return nil, fmt.Errorf("configuration file %s has no profile %q, found: %s", path, name, strings.Join(names, ", "))
+	}
+
+	for _, secret := range []*string{&p.ApplicationPassword, &p.Password, &p.AccessToken} {
+		if *secret, err = resolveSecret(*secret); err != nil {
+			return nil, fmt.Errorf("profile %q: %w", selected, err)
+		}
+	}
+	return &p, nil
+}
+
+// resolveSecret returns the value of a secret reference `env:NAME` or
+// `file:PATH`. Other values are returned as is.
+func resolveSecret(value string) (string, error) {
+	if name, ok := strings.CutPrefix(value, "env:"); ok {
+		secret, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s of secret reference is not set", name)
+		}
+		return secret, nil
+	}
+
+	if path, ok := strings.CutPrefix(value, "file:"); ok {
+		secret, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("unable to read secret reference: %w", err)
+		}
+		return strings.TrimSpace(string(secret)), nil
+	}
+
+	return value, nil
+}
@@ -0,0 +1,107 @@
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+const testConfigFile = `
+[default]
+aipe_url = "https://aipe.default.example"
+
+[prod]
+aipe_url             = "https://aipe.prod.example"
+application_username = "terraform"
+application_password = "env:SWP_TEST_PROD_SECRET"
+password             = "file:%s"
+scopes               = ["aipe:read"]
+`
+
+func writeConfigFile(t *testing.T, content string) {
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(secretFile, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(configFile, []byte(strings.ReplaceAll(content, "%s", secretFile)), 0o600); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	t.Setenv("SWP_CONFIG_FILE", configFile)
+}
+
+func TestLoadProfile(t *testing.T) {
+	writeConfigFile(t, testConfigFile)
+	t.Setenv("SWP_TEST_PROD_SECRET", "from-env")
+
+	defaultProfile, err := loadProfile("")
+	if err != nil || defaultProfile.AIPEURL != "https://aipe.default.example" {
+		t.Errorf("expected default profile, got %+v, %v", defaultProfile, err)
+	}
+
+	prod, err := loadProfile("prod")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if prod.AIPEURL != "https://aipe.prod.example" || prod.ApplicationPassword != "from-env" || prod.Password != "from-file" || len(prod.Scopes) != 1 {
+		t.Errorf("unexpected profile %+v", prod)
+	}
+
+	if _, err := loadProfile("staging"); err == nil || !strings.Contains(err.Error(), "found: default, prod") {
+		t.Errorf("expected unknown profile error, got %v", err)
+	}
+}
+
+func TestLoadProfileErrors(t *testing.T) {
+	for name, content := range map[string]string{
+		"unknown setting": "[default]\naipe_uri = \"https://aipe.example\"",
+		"missing secret":  "[default]\napplication_password = \"env:SWP_TEST_UNSET_SECRET\"",
+		"invalid file":    "[default\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			writeConfigFile(t, content)
+
+			if _, err := loadProfile(""); err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
+
+func TestLoadProfileWithoutConfigFile(t *testing.T) {
+	t.Setenv("SWP_CONFIG_FILE", filepath.Join(t.TempDir(), "missing"))
+
+	if p, err := loadProfile(""); err != nil || p.AIPEURL != "" {
+		t.Errorf("expected empty profile, got %+v, %v", p, err)
+	}
+	if _, err := loadProfile("prod"); err == nil {
+		t.Errorf("expected error for selected profile without configuration file")
+	}
+}
+
+func TestResolvePrecedence(t *testing.T) {
+	if value := resolve(types.StringValue("attribute"), "env", "profile"); value != "attribute" {
+		t.Errorf("expected attribute, got %s", value)
+	}
+	if value := resolve(types.StringNull(), "env", "profile"); value != "env" {
+		t.Errorf("expected env, got %s", value)
+	}
+	if value := resolve(types.StringNull(), "", "profile"); value != "profile" {
+		t.Errorf("expected profile, got %s", value)
+	}
+}
+
+func TestSettingsEnvIgnoredForSelectedProfile(t *testing.T) {
+	t.Setenv("SWP_TEST_SETTING", "env")
+
+	if value := settingsEnv("")("SWP_TEST_SETTING"); value != "env" {
+		t.Errorf("expected env without selected profile, got %s", value)
+	}
+	if value := settingsEnv("prod")("SWP_TEST_SETTING"); value != "" {
+		t.Errorf("expected environment to be ignored for selected profile, got %s", value)
+	}
+}
@@ -5,19 +5,29 @@ package provider
 
 import (
 	"context"
-	"net/http"
+	"crypto"
+	"fmt"
 	"os"
+	"slices"
 	"time"
 
 	"github.com/Serviceware/terraform-provider-swp/internal/aipe"
 	"github.com/Serviceware/terraform-provider-swp/internal/authenticator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/float64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"golang.org/x/sync/semaphore"
+	"golang.org/x/time/rate"
 )
 
 // Ensure ScaffoldingProvider satisfies various provider interfaces.
@@ -28,10 +38,36 @@ type AIPEProvider struct {
 }
 
 type AIPEProviderModel struct {
-	ApplicationUsername   types.String `tfsdk:"application_username"`
-	ApplicationPassword   types.String `tfsdk:"application_password"`
-	AuthenticatorRealmURL types.String `tfsdk:"authenticator_realm_url"`
-	AIPEURL               types.String `tfsdk:"aipe_url"`
+	Profile               types.String      `tfsdk:"profile"`
+	ApplicationUsername   types.String      `tfsdk:"application_username"`
+	ApplicationPassword   types.String      `tfsdk:"application_password"`
+	AuthenticatorRealmURL types.String      `tfsdk:"authenticator_realm_url"`
+	TokenURL              types.String      `tfsdk:"token_url"`
+	AIPEURL               types.String      `tfsdk:"aipe_url"`
+	AccessToken           types.String      `tfsdk:"access_token"`
+	CredentialCommand     []string          `tfsdk:"credential_command"`
+	AuthMethod            types.String      `tfsdk:"auth_method"`
+	PrivateKeyPEM         types.String      `tfsdk:"private_key_pem"`
+	PrivateKeyFile        types.String      `tfsdk:"private_key_file"`
+	PrivateKeyID          types.String      `tfsdk:"private_key_id"`
+	Username              types.String      `tfsdk:"username"`
+	Password              types.String      `tfsdk:"password"`
+	Scopes                []string          `tfsdk:"scopes"`
+	Audience              types.String      `tfsdk:"audience"`
+	TokenExtraParams      map[string]string `tfsdk:"token_extra_params"`
+	TokenRefreshFraction  types.Float64     `tfsdk:"token_refresh_fraction"`
+	MaxRetries            types.Int64       `tfsdk:"max_retries"`
+	RetryMaxWait          types.String      `tfsdk:"retry_max_wait"`
+	RequestTimeout        types.String      `tfsdk:"request_timeout"`
+	ProxyURL              types.String      `tfsdk:"proxy_url"`
+	CACertPEM             types.String      `tfsdk:"ca_cert_pem"`
+	CACertFile            types.String      `tfsdk:"ca_cert_file"`
+	ClientCert            types.String      `tfsdk:"client_cert"`
+	ClientKey             types.String      `tfsdk:"client_key"`
+	InsecureSkipVerify    types.Bool        `tfsdk:"insecure_skip_verify"`
+	RequestsPerSecond     types.Float64     `tfsdk:"requests_per_second"`
+	MaxConcurrentRequests types.Int64       `tfsdk:"max_concurrent_requests"`
+	SensitiveProperties   []string          `tfsdk:"sensitive_properties"`
 }
 
 func (p *AIPEProvider) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
@@ -42,24 +78,157 @@ func (p *AIPEProvider) Metadata(ctx context.Context, req provider.MetadataReques
 func (p *AIPEProvider) Schema(ctx context.Context, req provider.SchemaRequest, resp *provider.SchemaResponse) {
 	resp.Schema = schema.Schema{
 		Attributes: map[string]schema.Attribute{
+			"profile": schema.StringAttribute{
+				MarkdownDescription: "Name of the profile in the configuration file `~/.config/swp/config` or `SWP_CONFIG_FILE` to take settings from. Can also be set with `SWP_PROFILE`, defaults to the profile `default` if the file has one. Provider attributes take precedence over the profile. The `SWP_*` environment variables holding settings are ignored if a profile is selected with `profile` or `SWP_PROFILE`, and take precedence over the `default` profile otherwise",
+				Optional:            true,
+			},
 			"application_username": schema.StringAttribute{
-				MarkdownDescription: "Username for AIPE from user management",
+				MarkdownDescription: "Username for AIPE from user management, used as OAuth client ID",
 				Optional:            true,
 			},
 			"application_password": schema.StringAttribute{
-				MarkdownDescription: "Password for AIPE from user management",
+				MarkdownDescription: "Password for AIPE from user management, used as OAuth client secret. Not needed for `auth_method` `private_key_jwt`, optional for `password`",
+				Optional:            true,
+				Sensitive:           true,
+			},
+			"auth_method": schema.StringAttribute{
+				MarkdownDescription: "How the provider authenticates at the Authenticator: `client_secret_post` (default) and `client_secret_basic` send the application password in the body or header, `private_key_jwt` signs a client assertion with `private_key_pem` or `private_key_file`, `password` uses the resource owner password grant with `username` and `password`. Can also be set with `SWP_AUTH_METHOD`",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(authMethodNames()...),
+				},
+			},
+			"private_key_pem": schema.StringAttribute{
+				MarkdownDescription: "PEM encoded RSA, ECDSA or Ed25519 private key signing the client assertion of `private_key_jwt`. Can also be set with `SWP_PRIVATE_KEY_PEM`",
+				Optional:            true,
+				Sensitive:           true,
+				Validators: []validator.String{
+					stringvalidator.ConflictsWith(path.MatchRoot("private_key_file")),
+				},
+			},
+			"private_key_file": schema.StringAttribute{
+				MarkdownDescription: "Path of a file containing the private key of `private_key_jwt`, as alternative to `private_key_pem`. Can also be set with `SWP_PRIVATE_KEY_FILE`",
+				Optional:            true,
+			},
+			"private_key_id": schema.StringAttribute{
+				MarkdownDescription: "Key ID sent as `kid` header of the client assertion of `private_key_jwt`. Can also be set with `SWP_PRIVATE_KEY_ID`",
+				Optional:            true,
+			},
+			"username": schema.StringAttribute{
+				MarkdownDescription: "Username of the resource owner for `auth_method` `password`. Can also be set with `SWP_USERNAME`",
+				Optional:            true,
+			},
+			"password": schema.StringAttribute{
+				MarkdownDescription: "Password of the resource owner for `auth_method` `password`. Can also be set with `SWP_PASSWORD`",
 				Optional:            true,
 				Sensitive:           true,
 			},
 
 			"authenticator_realm_url": schema.StringAttribute{
-				MarkdownDescription: "URL of the Authenticatopr realm",
+				MarkdownDescription: "URL of the Authenticatopr realm. The token endpoint is discovered from its `/.well-known/openid-configuration`",
+				Optional:            true,
+			},
+			"token_url": schema.StringAttribute{
+				MarkdownDescription: "URL of the token endpoint, overrides the discovery from `authenticator_realm_url` which is not needed then. Can also be set with `SWP_TOKEN_URL`",
 				Optional:            true,
 			},
 			"aipe_url": schema.StringAttribute{
 				MarkdownDescription: "URL of the AIPE",
 				Optional:            true,
 			},
+			"access_token": schema.StringAttribute{
+				MarkdownDescription: "A pre-issued access token for the AIPE, which is used instead of authenticating at the Authenticator. Can also be set with `SWP_ACCESS_TOKEN`",
+				Optional:            true,
+				Sensitive:           true,
+			},
+			"credential_command": schema.ListAttribute{
+				MarkdownDescription: "A command and its arguments printing an access token as JSON like `{\"access_token\": \"...\", \"expires_at\": \"2025-01-01T12:00:00Z\"}`, which is used instead of authenticating at the Authenticator. The command is run again whenever the token is about to expire. `expires_at` is optional for JWTs",
+				ElementType:         types.StringType,
+				Optional:            true,
+				Validators: []validator.List{
+					listvalidator.SizeAtLeast(1),
+				},
+			},
+			"scopes": schema.ListAttribute{
+				MarkdownDescription: "Scopes requested with the token. The provider fails if the token has not been granted all of them",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"audience": schema.StringAttribute{
+				MarkdownDescription: "Audience requested with the token. The provider fails if the token has been issued for another audience",
+				Optional:            true,
+			},
+			"token_extra_params": schema.MapAttribute{
+				MarkdownDescription: "Additional parameters of the token request, e.g. `resource`. Parameters set by the provider itself like `grant_type` or `scope` cannot be overridden",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"token_refresh_fraction": schema.Float64Attribute{
+				MarkdownDescription: fmt.Sprintf("Fraction of the token lifetime after which the token is refreshed in the background, between 0.1 and 1. Defaults to %v", authenticator.DefaultRefreshFraction),
+				Optional:            true,
+				Validators: []validator.Float64{
+					float64validator.Between(0.1, 1),
+				},
+			},
+			"max_retries": schema.Int64Attribute{
+				MarkdownDescription: fmt.Sprintf("How often a failed AIPE request is retried, e.g. on a 429 or 503 response. Defaults to %d", aipe.DefaultMaxRetries),
+				Optional:            true,
+			},
+			"retry_max_wait": schema.StringAttribute{
+				MarkdownDescription: fmt.Sprintf("Maximum wait time between two retries as duration, e.g. `10s`. Defaults to `%s`", aipe.DefaultRetryMaxWait),
+				Optional:            true,
+			},
+			"request_timeout": schema.StringAttribute{
+				MarkdownDescription: fmt.Sprintf("Timeout of a single request to the AIPE or the Authenticator as duration, e.g. `1m`. Defaults to `%s`", defaultRequestTimeout),
+				Optional:            true,
+			},
+			"proxy_url": schema.StringAttribute{
+				MarkdownDescription: "URL of the proxy for all requests, e.g. `http://proxy:3128`. Defaults to the proxy of the `HTTPS_PROXY` and `NO_PROXY` environment variables",
+				Optional:            true,
+			},
+			"ca_cert_pem": schema.StringAttribute{
+				MarkdownDescription: "PEM encoded CA certificates trusted in addition to the system CAs, e.g. of an internal CA",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.ConflictsWith(path.MatchRoot("ca_cert_file")),
+				},
+			},
+			"ca_cert_file": schema.StringAttribute{
+				MarkdownDescription: "Path of a file containing the CA certificates, as alternative to `ca_cert_pem`",
+				Optional:            true,
+			},
+			"client_cert": schema.StringAttribute{
+				MarkdownDescription: "PEM encoded client certificate for mutual TLS. Use `file()` to read it from a file",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.AlsoRequires(path.MatchRoot("client_key")),
+				},
+			},
+			"client_key": schema.StringAttribute{
+				MarkdownDescription: "PEM encoded private key of `client_cert`",
+				Optional:            true,
+				Sensitive:           true,
+				Validators: []validator.String{
+					stringvalidator.AlsoRequires(path.MatchRoot("client_cert")),
+				},
+			},
+			"insecure_skip_verify": schema.BoolAttribute{
+				MarkdownDescription: "Skip the verification of TLS certificates. Only use this for testing",
+				Optional:            true,
+			},
+			"requests_per_second": schema.Float64Attribute{
+				MarkdownDescription: "Maximum rate of requests to the AIPE, shared by all resources and data sources. Retries count as requests. Not limited if not set",
+				Optional:            true,
+			},
+			"max_concurrent_requests": schema.Int64Attribute{
+				MarkdownDescription: "Maximum number of concurrent requests to the AIPE, shared by all resources and data sources. Not limited if not set",
+				Optional:            true,
+			},
+			"sensitive_properties": schema.ListAttribute{
+				MarkdownDescription: "Names of data object properties whose values are masked in the logs of the provider, e.g. properties with personal data",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
 		},
 	}
 }
@@ -73,41 +242,147 @@ func (p *AIPEProvider) Configure(ctx context.Context, req provider.ConfigureRequ
 		return
 	}
 
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+	profileName := resolve(data.Profile, os.Getenv("SWP_PROFILE"), "")
+	profile, err := loadProfile(profileName)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("profile"), "profile", err.Error())
+		return
+	}
+
+	// Settings are taken from the provider attributes first, then from the
+	// SWP_* environment variables and finally from the profile. An explicitly
+	// selected profile replaces the environment variables.
+	getenv := settingsEnv(profileName)
+	applicationUsername := resolve(data.ApplicationUsername, getenv("SWP_APPLICATION_USER_USERNAME"), profile.ApplicationUsername)
+	applicationPassword := resolve(data.ApplicationPassword, getenv("SWP_APPLICATION_USER_PASSWORD"), profile.ApplicationPassword)
+	authenticatorRealmURL := resolve(data.AuthenticatorRealmURL, getenv("SWP_AUTHENTICATOR_URL"), profile.AuthenticatorRealmURL)
+	tokenURL := resolve(data.TokenURL, getenv("SWP_TOKEN_URL"), profile.TokenURL)
+	aipeURL := resolve(data.AIPEURL, getenv("SWP_AIPE_URL"), profile.AIPEURL)
+	authMethod := resolve(data.AuthMethod, getenv("SWP_AUTH_METHOD"), profile.AuthMethod)
+	privateKeyID := resolve(data.PrivateKeyID, getenv("SWP_PRIVATE_KEY_ID"), profile.PrivateKeyID)
+	username := resolve(data.Username, getenv("SWP_USERNAME"), profile.Username)
+	password := resolve(data.Password, getenv("SWP_PASSWORD"), profile.Password)
+	audience := resolve(data.Audience, "", profile.Audience)
+
+	// Alternative settings are taken together from the same source.
+	accessToken, credentialCommand := data.AccessToken.ValueString(), data.CredentialCommand
+	if accessToken == "" && len(credentialCommand) == 0 {
+		accessToken = getenv("SWP_ACCESS_TOKEN")
+		// a login configured by attributes or environment variables beats the token of the profile
+		loginConfigured := data.ApplicationUsername.ValueString() != "" || getenv("SWP_APPLICATION_USER_USERNAME") != ""
+		if accessToken == "" && !loginConfigured {
+			accessToken, credentialCommand = profile.AccessToken, profile.CredentialCommand
+		}
+	}
+
+	privateKeyPEM, privateKeyFile := data.PrivateKeyPEM.ValueString(), data.PrivateKeyFile.ValueString()
+	if privateKeyPEM == "" && privateKeyFile == "" {
+		privateKeyPEM, privateKeyFile = getenv("SWP_PRIVATE_KEY_PEM"), getenv("SWP_PRIVATE_KEY_FILE")
+		if privateKeyPEM == "" && privateKeyFile == "" {
+			privateKeyFile = profile.PrivateKeyFile
+		}
+	}
+
+	scopes := data.Scopes
+	if scopes == nil {
+		scopes = profile.Scopes
 	}
 
-	applicationUsername := os.Getenv("SWP_APPLICATION_USER_USERNAME")
-	applicationPassword := os.Getenv("SWP_APPLICATION_USER_PASSWORD")
-	authenticatorRealmURL := os.Getenv("SWP_AUTHENTICATOR_URL")
-	aipeURL := os.Getenv("SWP_AIPE_URL")
+	if data.CACertPEM.ValueString() == "" && data.CACertFile.ValueString() == "" && profile.CACertFile != "" {
+		data.CACertFile = types.StringValue(profile.CACertFile)
+	}
+
+	if data.ProxyURL.ValueString() == "" && profile.ProxyURL != "" {
+		data.ProxyURL = types.StringValue(profile.ProxyURL)
+	}
+
+	client, diags := newHTTPClient(data)
+	resp.Diagnostics.Append(diags...)
 
-	if data.ApplicationUsername.ValueString() != "" {
-		applicationUsername = data.ApplicationUsername.ValueString()
+	if authMethod == "" {
+		authMethod = string(authenticator.AuthMethodClientSecretPost)
 	}
 
-	if data.ApplicationPassword.ValueString() != "" {
-		applicationPassword = data.ApplicationPassword.ValueString()
+	if !slices.Contains(authMethodNames(), authMethod) {
+		resp.Diagnostics.AddAttributeError(path.Root("auth_method"), "auth_method", fmt.Sprintf("auth_method must be one of %v, got: %q", authMethodNames(), authMethod))
+	}
+
+	for key := range data.TokenExtraParams {
+		if slices.Contains(authenticator.ReservedParams, key) {
+			resp.Diagnostics.AddAttributeError(path.Root("token_extra_params").AtMapKey(key), "token_extra_params", fmt.Sprintf("%s is set by the provider and cannot be overridden", key))
+		}
 	}
 
-	if data.AuthenticatorRealmURL.ValueString() != "" {
-		authenticatorRealmURL = data.AuthenticatorRealmURL.ValueString()
+	refreshFraction := authenticator.DefaultRefreshFraction
+	if !data.TokenRefreshFraction.IsNull() {
+		refreshFraction = data.TokenRefreshFraction.ValueFloat64()
 	}
 
-	if data.AIPEURL.ValueString() != "" {
-		aipeURL = data.AIPEURL.ValueString()
+	maxRetries := aipe.DefaultMaxRetries
+	if !data.MaxRetries.IsNull() {
+		maxRetries = int(data.MaxRetries.ValueInt64())
 	}
 
-	if applicationUsername == "" {
-		resp.Diagnostics.AddError("application_username", "application_username is required")
+	if maxRetries < 0 {
+		resp.Diagnostics.AddAttributeError(path.Root("max_retries"), "max_retries", "max_retries must not be negative")
+	}
+
+	retryMaxWait := aipe.DefaultRetryMaxWait
+	if data.RetryMaxWait.ValueString() != "" {
+		var err error
+		retryMaxWait, err = time.ParseDuration(data.RetryMaxWait.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("retry_max_wait"), "retry_max_wait", fmt.Sprintf("retry_max_wait must be a duration like 30s: %s", err))
+		}
+	}
+
+	var rateLimiter *rate.Limiter
+	if !data.RequestsPerSecond.IsNull() {
+		requestsPerSecond := data.RequestsPerSecond.ValueFloat64()
+		if requestsPerSecond <= 0 {
+			resp.Diagnostics.AddAttributeError(path.Root("requests_per_second"), "requests_per_second", "requests_per_second must be positive")
+		}
+		// the burst allows the requests of one second at once
+		rateLimiter = rate.NewLimiter(rate.Limit(requestsPerSecond), max(1, int(requestsPerSecond)))
 	}
 
-	if applicationPassword == "" {
-		resp.Diagnostics.AddError("application_password", "application_password is required")
+	var inFlight *semaphore.Weighted
+	if !data.MaxConcurrentRequests.IsNull() {
+		if data.MaxConcurrentRequests.ValueInt64() < 1 {
+			resp.Diagnostics.AddAttributeError(path.Root("max_concurrent_requests"), "max_concurrent_requests", "max_concurrent_requests must be at least 1")
+		}
+		inFlight = semaphore.NewWeighted(data.MaxConcurrentRequests.ValueInt64())
 	}
 
-	if authenticatorRealmURL == "" {
-		resp.Diagnostics.AddError("authenticator_realm_url", "authenticator_realm_url is required")
+	var privateKey crypto.Signer
+	if accessToken != "" && len(credentialCommand) > 0 {
+		resp.Diagnostics.AddError("access_token", "Only one of access_token and credential_command can be set")
+	} else if accessToken == "" && len(credentialCommand) == 0 {
+		if applicationUsername == "" {
+			resp.Diagnostics.AddError("application_username", "application_username is required")
+		}
+
+		switch authenticator.AuthMethod(authMethod) {
+		case authenticator.AuthMethodClientSecretPost, authenticator.AuthMethodClientSecretBasic:
+			if applicationPassword == "" {
+				resp.Diagnostics.AddError("application_password", "application_password is required")
+			}
+		case authenticator.AuthMethodPrivateKeyJWT:
+			var diags diag.Diagnostics
+			privateKey, diags = loadPrivateKey(privateKeyPEM, privateKeyFile)
+			resp.Diagnostics.Append(diags...)
+		case authenticator.AuthMethodPassword:
+			if username == "" {
+				resp.Diagnostics.AddError("username", "username is required for auth_method password")
+			}
+			if password == "" {
+				resp.Diagnostics.AddError("password", "password is required for auth_method password")
+			}
+		}
+
+		if authenticatorRealmURL == "" && tokenURL == "" {
+			resp.Diagnostics.AddError("authenticator_realm_url", "authenticator_realm_url or token_url is required")
+		}
 	}
 
 	if aipeURL == "" {
@@ -120,15 +395,33 @@ func (p *AIPEProvider) Configure(ctx context.Context, req provider.ConfigureRequ
 
 	authenticatorClient := authenticator.AuthenticatorClient{
 		Client:              client,
+		AuthMethod:          authenticator.AuthMethod(authMethod),
 		ApplicationUsername: applicationUsername,
 		ApplicationPassword: applicationPassword,
+		PrivateKey:          privateKey,
+		KeyID:               privateKeyID,
+		Username:            username,
+		Password:            password,
 		URL:                 authenticatorRealmURL,
+		TokenURL:            tokenURL,
+		AccessToken:         accessToken,
+		CredentialCommand:   credentialCommand,
+		RefreshFraction:     refreshFraction,
+		Scopes:              scopes,
+		Audience:            audience,
+		ExtraParams:         data.TokenExtraParams,
 	}
 
 	aipeClient := aipe.AIPEClient{
 		HTTPClient:    client,
 		URL:           aipeURL,
 		Authenticator: &authenticatorClient,
+		MaxRetries:    maxRetries,
+		RetryMaxWait:  retryMaxWait,
+		RateLimiter:   rateLimiter,
+		InFlight:      inFlight,
+
+		SensitiveProperties: data.SensitiveProperties,
 	}
 
 	tflog.Info(ctx, "Successfully configured AIPE provider")
@@ -136,16 +429,77 @@ func (p *AIPEProvider) Configure(ctx context.Context, req provider.ConfigureRequ
 	resp.ResourceData = &aipeClient
 }
 
+// loadPrivateKey parses the private key of private_key_jwt from the PEM string or file.
+func loadPrivateKey(privateKeyPEM string, privateKeyFile string) (crypto.Signer, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	data := []byte(privateKeyPEM)
+	if privateKeyFile != "" {
+		var err error
+		data, err = os.ReadFile(privateKeyFile)
+		if err != nil {
+			diags.AddAttributeError(path.Root("private_key_file"), "private_key_file", fmt.Sprintf("Unable to read private key: %s", err))
+			return nil, diags
+		}
+	}
+
+	if len(data) == 0 {
+		diags.AddError("private_key_pem", "private_key_pem or private_key_file is required for auth_method private_key_jwt")
+		return nil, diags
+	}
+
+	privateKey, err := authenticator.ParsePrivateKey(data)
+	if err != nil {
+		diags.AddError("private_key_pem", fmt.Sprintf("Unable to parse private key: %s", err))
+		return nil, diags
+	}
+	return privateKey, diags
+}
+
+func authMethodNames() []string {
+	names := make([]string, 0, len(authenticator.AuthMethods))
+	for _, method := range authenticator.AuthMethods {
+		names = append(names, string(method))
+	}
+	return names
+}
+
+// resolve returns the value of a setting from the provider attribute, the
+// environment variable or the profile, in this order of precedence.
+func resolve(attribute types.String, envValue string, profileValue string) string {
+	if attribute.ValueString() != "" {
+		return attribute.ValueString()
+	}
+	if envValue != "" {
+		return envValue
+	}
+	return profileValue
+}
+
+// settingsEnv returns the lookup of the SWP_* environment variables holding
+// settings. If a profile has been selected explicitly, the variables are
+// ignored, so variables left over in the shell for another tenant are not
+// mixed with the settings of the profile.
+func settingsEnv(profileName string) func(envVar string) string {
+	if profileName != "" {
+		return func(string) string { return "" }
+	}
+	return os.Getenv
+}
+
 func (p *AIPEProvider) Resources(ctx context.Context) []func() resource.Resource {
 	return []func() resource.Resource{
 		NewDataObjectResource,
 		NewDataObjectLinkResource,
+		NewDataObjectLinkMemberResource,
+		NewDataObjectLinksResource,
 	}
 }
 
 func (p *AIPEProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
 	return []func() datasource.DataSource{
 		NewDataObjectDataSource,
+		NewDataObjectsDataSource,
 	}
 }
 
@@ -1,14 +1,27 @@
 package provider
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
 	"net/http"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/Serviceware/terraform-provider-swp/internal/aipe"
+	"github.com/Serviceware/terraform-provider-swp/internal/aipetest"
 	"github.com/Serviceware/terraform-provider-swp/internal/authenticator"
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
 	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
 )
 
 var testAccProtoV6ProviderFactories = map[string]func() (tfprotov6.ProviderServer, error){
@@ -24,7 +37,19 @@ var requiredEnvironmentVariables = []string{
 	"SWP_AIPE_URL",
 }
 
+// testAccPreCheck configures the acceptance tests against the AIPE given by
+// the SWP_* environment variables. If none of them is set, the tests run
+// offline against an in-memory fake.
 func testAccPreCheck(t *testing.T) {
+	if !slices.ContainsFunc(requiredEnvironmentVariables, func(envVar string) bool { return os.Getenv(envVar) != "" }) {
+		server := aipetest.NewServer(t)
+		t.Setenv("SWP_APPLICATION_USER_USERNAME", server.ClientID)
+		t.Setenv("SWP_APPLICATION_USER_PASSWORD", server.ClientSecret)
+		t.Setenv("SWP_AUTHENTICATOR_URL", server.RealmURL())
+		t.Setenv("SWP_AIPE_URL", server.URL)
+		t.Setenv("SWP_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+	}
+
 	for _, envVar := range requiredEnvironmentVariables {
 		if os.Getenv(envVar) == "" {
 			t.Fatalf("Environment variable %s must be set for acceptance tests", envVar)
@@ -45,3 +70,110 @@ func testAccPreCheck(t *testing.T) {
 		Authenticator: &authenticatorClient,
 	}
 }
+
+func TestLoadPrivateKey(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, _ := x509.MarshalECPrivateKey(key)
+	keyFile := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if privateKey, diags := loadPrivateKey("", keyFile); diags.HasError() || !key.Equal(privateKey) {
+		t.Errorf("expected key from file, got %v", diags)
+	}
+	if _, diags := loadPrivateKey("", filepath.Join(t.TempDir(), "missing.pem")); !diags.HasError() {
+		t.Errorf("expected error for missing file")
+	}
+	if _, diags := loadPrivateKey("not a key", ""); !diags.HasError() {
+		t.Errorf("expected error for invalid key")
+	}
+	if _, diags := loadPrivateKey("", ""); !diags.HasError() {
+		t.Errorf("expected error without key")
+	}
+}
+
+func TestAccProviderProfile(t *testing.T) {
+	server := aipetest.NewServer(t)
+	for _, envVar := range requiredEnvironmentVariables {
+		t.Setenv(envVar, "")
+	}
+	// left over from another tenant, ignored for the selected profile
+	t.Setenv("SWP_APPLICATION_USER_PASSWORD", "secret-of-another-tenant")
+	t.Setenv("SWP_AIPE_URL", "https://aipe.other-tenant.example")
+	t.Setenv("SWP_TEST_PROFILE_SECRET", server.ClientSecret)
+	writeConfigFile(t, fmt.Sprintf(`
+[offline]
+aipe_url                = %q
+authenticator_realm_url = %q
+application_username    = %q
+application_password    = "env:SWP_TEST_PROFILE_SECRET"
+`, server.URL, server.RealmURL(), server.ClientID))
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+provider "swp" {
+	profile = "offline"
+}
+
+resource "swp_aipe_data_object" "from_profile" {
+	type = "test-object"
+	properties = {
+		"foo" = "bar"
+	}
+}
+`,
+				Check: resource.TestCheckResourceAttrSet("swp_aipe_data_object.from_profile", "id"),
+			},
+		},
+	})
+}
+
+func TestAccProviderScopeNotGranted(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		PreCheck:                 func() { testAccPreCheck(t) },
+		Steps: []resource.TestStep{
+			{
+				Config: `
+provider "swp" {
+	scopes = ["aipe:admin"]
+}
+
+resource "swp_aipe_data_object" "unauthorized" {
+	type = "test-object"
+	properties = {
+		"foo" = "bar"
+	}
+}
+`,
+				ExpectError: regexp.MustCompile("Invalid provider configuration"),
+			},
+		},
+	})
+}
+
+func TestScopeNotGrantedIsConfigurationError(t *testing.T) {
+	server := aipetest.NewServer(t)
+	client := &aipe.AIPEClient{
+		HTTPClient: server.Client(),
+		URL:        server.URL,
+		Authenticator: &authenticator.AuthenticatorClient{
+			Client:              server.Client(),
+			ApplicationUsername: server.ClientID,
+			ApplicationPassword: server.ClientSecret,
+			URL:                 server.RealmURL(),
+			Scopes:              []string{"aipe:admin"},
+		},
+	}
+
+	_, err := client.CreateObject(context.Background(), "test-object", map[string]interface{}{"foo": "bar"})
+	diags := clientErrorDiagnostics("Client Error", "Unable to create example", err, nil)
+
+	if len(diags) != 1 || diags[0].Summary() != "Invalid provider configuration" || !strings.Contains(diags[0].Detail(), "requested scopes aipe:admin") {
+		t.Errorf("unexpected diagnostics %v", diags)
+	}
+}